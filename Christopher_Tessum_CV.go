@@ -2,438 +2,426 @@ package main
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"fmt"
 	"html/template"
-	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/chromedp"
 	"github.com/nickng/bibtex"
 )
 
-var bibs = []string{
-	"cv.bib",
-	"Posters.bib",
-	"Presentations.bib",
-	"inprep.bib",
-}
-
 type Section struct {
-	Name      template.HTML
-	Items     []Item
-	Citations []template.HTML
+	ID        string          `yaml:"id"`
+	Name      template.HTML   `yaml:"name"`
+	Items     []Item          `yaml:"items"`
+	Citations []template.HTML `yaml:"citations"`
+
+	// Query, if set, selects the citations from the bibliographies
+	// instead. Include and Exclude add or remove entries by key.
+	Query   *Query          `yaml:"query"`
+	Include []template.HTML `yaml:"include"`
+	Exclude []template.HTML `yaml:"exclude"`
+
+	// Sort, if set, orders the citations by publication date.
+	Sort *Sort `yaml:"sort"`
+	// GroupByYear renders the citations under a heading for each year.
+	GroupByYear bool `yaml:"group-by-year"`
+	// Authors, if set, shortens long author lists.
+	Authors *Truncation `yaml:"authors"`
+	// JSONResume is the section of the JSON Resume schema that the items
+	// are exported to; see jsonResumeSections.
+	JSONResume string `yaml:"json-resume"`
+
+	line          int
+	citationLines map[template.HTML]int
 }
 
 type Item struct {
-	Name, Time, Description template.HTML
-}
-
-var cv = []Section{
-	{
-		Name: "Professional Appointments",
-		Items: []Item{
-			{
-				Name:        "Assistant Professor—University of Illinois at Urbana-Champaign",
-				Time:        "2020–present",
-				Description: "Department of Civil and Environmental Engineering",
-			},
-			{
-				Name:        "Research Scientist—University of Washington",
-				Time:        "2016–2019",
-				Description: "Department of Civil and Environmental Engineering",
-			},
-			{
-				Name:        "Postdoctoral Associate—University of Minnesota",
-				Time:        "2015–2016",
-				Description: "Department of Bioproducts and Biosystems Engineering",
-			},
-		},
-	},
-	{
-		Name: "Education",
-		Items: []Item{
-			{
-				Name: "Ph.D., Civil, Environmental, and Geo- Engineering (public health minor)—University of Minnesota",
-				Time: "2009–2014",
-			},
-			{
-				Name: "B.M.E., Mechanical Engineering (<i>cum laude</i>)—University of Minnesota",
-				Time: "2002–2006",
-			},
-		},
-	},
-	{
-		Name: "Peer-Reviewed Publications <small>(*=corresponding author; self and advisees are underlined)</small>",
-		Citations: []template.HTML{
-			"Goodkind2025", "guo2024uncertainty", "yang2024atmospheric", "park2024",
-			"giang2024", "Peshin_2024", "Schollaert_2024", "Schollaert2023", "ywang2023", "Nawaz2023",
-			"gallagher2023", "jackson2023city", "thind2022environmental",
-			"yuzhou2022ej", "kleiman2022", "mtessum2022",
-			"thakrar2022global", "develyn2022wildfire", "wu2021reduced",
-			"Balasubramanian2021", "DomingoAg2021", "TessumEJ2021", "KelpNN2020",
-			"Thakrar2020", "ThindEGU2019",
-			"Dimanchev2019", "Gilmore2019", "GoodkindISRM2019", "HillCorn2019", "TessumEIO2019", "LiuTrans2018",
-			"PaolellaGrid2018", "Thakrar2017", "Chang2017", "Tessum2017a", "Keeler2016", "Touchaei2016",
-			"Tessum2015a", "Tessum2014a", "Hu2014a", "Tessum2012", "Millet2012",
-		},
-	},
-	{
-		Name: "Preprints and Manuscripts Submitted for Review <small>(*=corresponding author; self and advisees are underlined)</small>",
-		Citations: []template.HTML{
-			"koolik2025methodological", "wang2025trade", "yang2025atmospheric", "kazemi2024aidovecl", "KelpNN2018",
-		},
-	},
-	// {
-	// 	Name: "Manuscripts in Preparation <small>(*=corresponding author)</small>",
-	// 	Citations: []template.HTML{
-	// 		"ChamblissiF2018", "MullerPolicy2018",
-	// 		"ThakrarInMAP2018",
-	// 	},
-	// },
-	{
-		Name:      "Reports and Other Publications",
-		Citations: []template.HTML{"SABBenMAP2024", "Tessum2010a", "Tessum2010"},
-	},
-	{
-		Name:      "Conference Papers",
-		Citations: []template.HTML{"park2022learned", "koloutsou2022cloud"},
-	},
-	{
-		Name: "Invited Presentations",
-		Citations: []template.HTML{
-			"Tessum2025SCM",
-			"Tessum2024mena", "Tessum2024CMAS", "Tessum2024nasaAI",
-			"Tessum2023AGU", "Tessum2023UVic", "Tessum2023NASA614",
-			"Tessum2022HAQAST", "Tessum2022NASAGMAO",
-			"Tessum2022AMS", "Tessum2021AGU_EJ",
-			"Tessum2021UW", "Tessum2021EPRI", "Tessum2021EPAWebinar",
-			"Tessum2021CACHET", "Tessum2021LBNLEAEI", "Tessum2021C40Webinar",
-			"Tessum2021AMS", "Tessum2020ACM",
-			"Tessum2017EIC", "Tessum2017CRC", "TessumHR2015", "TessumMEHA2015", "Tessum2014LBNL",
-			"Tessum2014NatCap", "TessumBeiDa2013", "TessumChinaCDC2013", "Tessum2013AWMA",
-			"TessumSETAC2012", "TessumPeking2011", "TessumMAS2011",
-		},
-	},
-	{
-		Name: "Conference Presentations",
-		Citations: []template.HTML{
-			"kim2024agu", "yang2024agu", "swang2024agu", "park2024agu", "liu2024agu", "guo2024agu", "fatima2024agu",
-			"yang2023agu", "swang2023agu", "ran2023agu",
-			"park2023agu", "jliu2023agu", "fatima2023agu", "guo2023agu", "park2023iama",
-			"Park2023AMS", "gallagher2022scaling", "singh2022distributional", "Park2022NeurIPS", "Guo2022ACM",
-			"Yang2022ACM", "Wang2022Tweeds", "wang2022addressing", "Tessum2022ISES", "Tessum2022SIAM", "Xiaokai2021IAMA",
-			"Tessum2021AGU_SrcAppt", "Shiyuan2021AGU",
-			"tessum2020predicting", "anenberg2020recent", "stylianou2019spatially", "kelp2019deep",
-			"Tessum2018CMASEIEIO", "Tessum2018CMASInMAP", "Tessum2018CMASNN",
-			"Tessum2018ISEE", "Tessum2016Cobenefits", "Tessum2016ISEEa",
-			"Tessum2016ISEEb", "Marshall2016HEI", "TessumAAAR2015",
-			"TessumMSI2015", "Tessum2014AAAR", "Tessum2014ISEE",
-			"Tessum2013ISEE", "Tessum2013MSI",
-			"TessumE32011", "TessumIonE2011", "TessumLCA2011", "TessumISEE2011", "TessumMSI2011",
-			"TessumE32010", "TessumBrazil2009",
-		},
-	},
-	{
-		Name: "Teaching Experience",
-		Items: []Item{
-			{
-				Name: "Developed and taught 'CEE 492: Data Science for Civil and Environmental Engineering'",
-				Time: "Fall 2020–Present",
-			},
-			{
-				Name: "Taught 'CEE 202: Engineering Risk and Uncertainty'",
-				Time: "Spring 2021–Present",
-			},
-			{
-				Name: "Guest lectures in life cycle assessment, air pollution, and health to undergraduate and graduate students",
-				Time: "2015–Present",
-			},
-			{
-				Name: "Teaching Assistant: Civil Engineering 5561: Air Quality Engineering, University of Minnesota",
-				Time: "2013",
-			},
-			{
-				Name: "English Teacher: Instituto Cultural Peruano Norteamericano, Chiclayo, Peru",
-				Time: "2008",
-			},
-		},
-	},
-	{
-		Name: "Professional Experience",
-		Items: []Item{
-			{
-				Name: "Owner/Partner: CT Consulting LLC, Enviromind LLC",
-				Time: "2008–2023",
-			},
-			{
-				Name: "Energy Auditor: Energy Management Solutions, Inc.",
-				Time: "2007–2008",
-			},
-			{
-				Name: "Aerodynamics Intern: Volvo Car Corporation",
-				Time: "2006",
-			},
-			{
-				Name: "Automation Intern: Voith Paper AG",
-				Time: "2006",
-			},
-		},
-	},
-	/*{
-		Name: "Honors and Awards",
-		Items: []Item{
-			{
-				Name: "Third place student poster award: American Center for Life Cycle Analysis Annual Conference",
-				Time: "2011",
-			},
-			{
-				Name: "Admission to First Annual Fulbright US–Brazil Biofuels Short Course",
-				Time: "2009",
-			},
-			{
-				Name: "National Merit Scholarship	",
-				Time: "2002–2006",
-			},
-		},
-	},*/
-	{
-		Name: "Synergistic Activities",
-		Items: []Item{
-			{
-				Name: "Developer of the InMAP air quality model (https://inmap.run), which has been downloaded 6,600 times and has a user forum with 191 members",
-				Time: "2012–present",
-			},
-			{
-				Name: "Member of US EPA Science Advisory Committee for 'Review of Air Pollution Benefits Methods and Environmental Benefits Mapping and Analysis Program (BenMAP) Tool'",
-				Time: "2023",
-			},
-			{
-				Name: "Facilitator for the UIUC Grainger College of Engineering 2023 summer workshop series on 'Incorporating Computing into Engineering Curriculum'",
-				Time: "2023",
-			},
-			{
-				Name: "Member of Health Effects Institute (HEI) panel of experts to commission a study and write a report about of diesel fleet renewal in the US",
-				Time: "2023–present",
-			},
-			{
-				Name: "Member of <i>GeoHealth</i> Early Career Editorial Board",
-				Time: "2024–present",
-			},
-		},
-	},
-}
-
-var cv2Page = []Section{
-	cv[0],
-	cv[1],
-	{
-		Name: "Selected Peer-Reviewed Publications <small>(*=corresponding author)</small>",
-		Citations: []template.HTML{
-			"wu2021reduced",
-			"Balasubramanian2021", "DomingoAg2021",
-			"TessumEJ2021", "KelpNN2020",
-			"Thakrar2020", "ThindEGU2019",
-			"Dimanchev2019", "GoodkindISRM2019", "HillCorn2019", "TessumEIO2019", "LiuTrans2018",
-			"PaolellaGrid2018", "Tessum2017a",
-			"Tessum2015a", "Tessum2014a", "Hu2014a", "Tessum2012", "Millet2012",
-		},
-	},
-	//cv[3],
-	func() Section {
-		x := cv[9]
-		x.Name = "Scientific, Technical, and Management Experience"
-		x.Items = []Item{x.Items[0], x.Items[3]}
-		return x
-	}(),
-}
-
-var resume = []Section{
-	{
-		Name: "Professional Appointments",
-		Items: []Item{
-			{
-				Name:        "Research Scientist—University of Washington",
-				Time:        "2016–Present",
-				Description: "Department of Civil and Environmental Engineering",
-			},
-			{
-				Name:        "Postdoctoral Associate—University of Minnesota",
-				Time:        "2015–2016",
-				Description: "Department of Bioproducts and Biosystems Engineering",
-			},
-		},
-	},
-	{
-		Name: "Education",
-		Items: []Item{
-			{
-				Name: "Ph.D., Civil, Environmental, and Geo- Engineering (public health minor)—University of Minnesota",
-				Time: "2009–2014",
-			},
-			{
-				Name: "B.M.E., Mechanical Engineering (<i>cum laude</i>)—University of Minnesota",
-				Time: "2002–2006",
-			},
-		},
-	},
-	{
-		Name: "Selected Publications <span style='font-variant:normal !important'><small>(*=corresponding author; full list at <a href=https://bit.ly/2DzkZoO>https://bit.ly/2DzkZoO</a>)</small></span>",
-		Citations: []template.HTML{
-			"KelpNN2018", "Tessum2017a", "Tessum2014a",
-		},
-	},
-	{
-		Name: "Programming Languages <span style='font-variant:normal !important'><small>(In order of experience)</small></span>",
-		Items: []Item{
-			{
-				Name: "Go (Golang); Python; R; Javascript; SQL; FORTRAN; C; MATLAB; LabVIEW",
-			},
-		},
-	},
-	{
-		Name: "Libraries and Frameworks",
-		Items: []Item{
-			{
-				Name: "Tensorflow; Kubernetes; HPC; Google Cloud Platform; Git/Github; Travis CI; PostGIS; React",
-			},
-		},
-	},
-	{
-		Name: "Open-Source Projects <span style='font-variant:normal !important'><small>(<a href=https://github.com/ctessum>https://github.com/ctessum</a>)</small></span>",
-		Items: []Item{
-			{
-				Name: "<a href=https://github.com/spatialmodel/inmap>https://github.com/spatialmodel/inmap</a>; <a href=https://github.com/gonum/plot/>https://github.com/gonum/plot/</a>",
-			},
-		},
-	},
-	{
-		Name: "Other Professional Experience",
-		Items: []Item{
-			{
-				Name: "English Teacher: Instituto Cultural Peruano Norteamericano; Chiclayo, Peru",
-				Time: "2008",
-			},
-			{
-				Name: "Engineer: Energy Management Solutions, Inc.; Minneapolis, MN",
-				Time: "2007–2008",
-			},
-			{
-				Name: "Aerodynamics Intern: Volvo Car Corporation; Gothenburg, Sweden",
-				Time: "2006",
-			},
-			{
-				Name: "Automation Intern: Voith Paper AG; Heidenheim an der Brenz, Germany",
-				Time: "2006",
-			},
-		},
-	},
-	{
-		Name: "Professional Service",
-		Items: []Item{
-			{
-				Name: "Grant Application Reviewer: NSF, Health Effects Institute, and US EPA",
-			},
-			{
-				Name: "Report Peer-Reviewer: US Department of Energy",
-			},
-			{
-				Name: `Journal Peer-Reviewer:
-				<i>Nature</i>,
-				<i>Science</i>,
-				<i>Proceedings of the National Academy of Sciences of the USA</i>,
-				<i>Nature Sustainability</i>,
-				<i>Nature Communications</i>,
-				<i>Environmental Science and Technology</i>,
-				<i>Atmospheric Environment</i>,  <i>Environmental Research Letters</i>,
-				<i>Proceedings of the Royal Society of London A</i>,
-				<i>International Journal of Geographical Information Science</i>,
-				<i>GeoHealth</i>, <i>Journal of Advances in Modeling Earth Systems</i>`,
-			},
-			{
-				Name: "Member: American Geophysical Union (AGU) and Association of Environmental Engineering and Science Professors (AEESP)",
-			},
-		},
-	},
+	ID          string        `yaml:"id"`
+	Name        template.HTML `yaml:"name"`
+	Time        template.HTML `yaml:"time"`
+	Description template.HTML `yaml:"description"`
+
+	line int
 }
 
-func main() {
+// A rendering is a document with its sections resolved, ready to be
+// written in one of the output formats.
+type rendering struct {
+	Document
+	Sections []Section
+	Owner    Owner
+	Date     time.Time // when the document was built
+
+	citations      map[template.HTML]*bibEntry
+	bibliographies []string
+	style          Style
+	marks          *nameMarks
+}
 
-	render(cv, "Christopher_Tessum_CV.pdf")
+// rendering resolves doc for output.
+func (d *Data) rendering(doc Document, citations map[template.HTML]*bibEntry) (*rendering, error) {
+	sections, err := d.Resolve(doc)
+	if err != nil {
+		return nil, err
+	}
+	style, err := doc.style()
+	if err != nil {
+		return nil, fmt.Errorf("document %s: %v", doc.ID, err)
+	}
+	return &rendering{
+		Document:       doc,
+		Sections:       sections,
+		Owner:          d.Owner,
+		Date:           time.Now(),
+		citations:      citations,
+		bibliographies: d.Bibliographies,
+		style:          style,
+		marks:          d.marks(),
+	}, nil
+}
 
-	render(cv2Page, "Christopher_Tessum_CV_2page.pdf")
+// ref formats the citation with the given key, shortening its author list
+// with authors if set.
+func (r *rendering) ref(key template.HTML, authors *Truncation) (template.HTML, error) {
+	elem, ok := r.citations[key]
+	if !ok {
+		return "", fmt.Errorf("invalid citation key %s", key)
+	}
+	s, err := r.style.Format(elem.withMarks(r.marks).withAuthors(authors))
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", key, err)
+	}
+	return template.HTML(s), nil
+}
 
-	render(resume, "Christopher_Tessum_Resume.pdf")
+// groups returns the citations of s, grouped by year if s asks for it or
+// otherwise as a single group.
+func (r *rendering) groups(s Section) []citationGroup {
+	if s.GroupByYear {
+		return groupByYear(r.citations, s.Citations)
+	}
+	return []citationGroup{{Start: len(s.Citations), Citations: s.Citations}}
+}
+
+func render(r *rendering, templateFile, filename string) error {
+	b, err := renderHTML(r, templateFile, false)
+	if err != nil {
+		return err
+	}
+	if err := printPDF(b, r.Print, r.chromePageTemplate(r.Header), r.chromePageTemplate(r.Footer), filename); err != nil {
+		return err
+	}
+	// Chrome only takes the title from the HTML.
+	return setPDFInfo(filename, r.pdfInfo())
 }
 
-func render(cv []Section, filename string) {
-	citations := parseBibtex(bibs)
+// writeHTML writes r as a web page, using the same template as for the
+// PDF but with its styles included, so that the page loads nothing else.
+func writeHTML(r *rendering, templateFile, filename string) error {
+	b, err := renderHTML(r, templateFile, true)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b, 0644)
+}
 
+// renderHTML executes the HTML template for r. If standalone is set, the
+// template's standalone function reports true, and the page should not
+// refer to stylesheets or scripts elsewhere.
+func renderHTML(r *rendering, templateFile string, standalone bool) ([]byte, error) {
 	tmpl, err := template.New("cv").Funcs(map[string]interface{}{
-		"ref": formatCitationFunc(citations),
-	}).ParseFiles("Christopher_Tessum_CV_template.html")
-	check(err)
+		"standalone": func() bool { return standalone },
+		"ref":        r.ref,
+		"legend":     r.legend,
+		"owner":      func() Owner { return r.Owner },
+		"contact": func() []template.HTML {
+			var lines []template.HTML
+			for _, l := range r.Owner.contactLines() {
+				lines = append(lines, template.HTML(l))
+			}
+			return lines
+		},
+		"title":    r.title,
+		"subject":  func() string { return r.Subject },
+		"keywords": func() string { return strings.Join(r.Keywords, ", ") },
+		"groupByYear": func(keys []template.HTML) []citationGroup {
+			return groupByYear(r.citations, keys)
+		},
+	}).ParseFiles(templateFile)
+	if err != nil {
+		return nil, err
+	}
 
 	var b bytes.Buffer
-	check(err)
-	check(tmpl.ExecuteTemplate(&b, "Christopher_Tessum_CV_template.html", cv))
+	if err := tmpl.ExecuteTemplate(&b, filepath.Base(templateFile), r.Sections); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// A bibEntry is a BibTeX entry along with the location where it is defined.
+type bibEntry struct {
+	*bibtex.BibEntry
+	File string
+	Line int
+
+	// marks are what is marked on the entry's author and editor names.
+	marks *nameMarks
+	// authors shortens the author list, if set.
+	authors *Truncation
+	// links is the link mode; see linkModes.
+	links string
+}
+
+// withMarks returns a copy of e whose names are marked according to m.
+func (e *bibEntry) withMarks(m *nameMarks) *bibEntry {
+	c := *e
+	c.marks = m
+	return &c
+}
 
-	printPDF(b.Bytes(), filename)
+// withAuthors returns a copy of e whose author list is shortened by t.
+func (e *bibEntry) withAuthors(t *Truncation) *bibEntry {
+	c := *e
+	c.authors = t
+	return &c
+}
+
+// names parses the named name-list field, such as author or editor.
+func (e *bibEntry) names(field string) []name {
+	names := parseNames(e.rawField(field))
+	m := e.marks
+	if m == nil {
+		m = &nameMarks{markers: &defaultMarkers}
+	}
+	markPeople(names, m.people, entryDate(e)[0])
+	if field == "author" {
+		markAuthors(names, m.markers, parseNames(e.rawField("corresponding")), parseNames(e.rawField("equalcontrib")))
+		names = e.authors.apply(names)
+	}
+	return names
+}
+
+// rawField returns the undecoded value of the named field, or "" if it is
+// not set.
+func (e *bibEntry) rawField(name string) string {
+	if v, ok := e.Fields[name]; ok && v != nil {
+		return v.String()
+	}
+	return ""
+}
+
+// field returns the value of the named field decoded from LaTeX to HTML,
+// or "" if it is not set.
+func (e *bibEntry) field(name string) string {
+	if verbatimFields[name] {
+		return decodeVerbatim(e.rawField(name))
+	}
+	return decodeLaTeX(e.rawField(name))
+}
+
+func (e *bibEntry) has(name string) bool {
+	return e.field(name) != ""
+}
+
+// hasAny reports whether any of the named fields is set.
+func (e *bibEntry) hasAny(names ...string) bool {
+	for _, n := range names {
+		if e.has(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// year returns the year of publication, taken from the BibLaTeX date field
+// if there is no year field.
+func (e *bibEntry) year() string {
+	if y := e.field("year"); y != "" {
+		return y
+	}
+	y, _, _ := strings.Cut(e.field("date"), "-")
+	return y
+}
+
+// verbatimFields hold identifiers rather than text, so LaTeX markup such as
+// dashes and ties is not interpreted in them.
+var verbatimFields = map[string]bool{
+	"url": true, "doi": true, "eprint": true, "file": true,
+	"isbn": true, "issn": true, "pmid": true,
+}
+
+// requiredFields lists the fields that each supported entry type must
+// have. Alternatives are separated by "|".
+var requiredFields = map[string][]string{
+	"article":       {"author", "title", "journal", "year|date"},
+	"inproceedings": {"author", "title", "booktitle", "year|date", "address"},
+	"techreport":    {"author", "title", "institution", "year|date", "address"},
+	"incollection":  {"author", "title", "booktitle", "editor", "publisher", "pages", "year|date"},
+	"book":          {"author|editor", "title", "publisher", "year|date"},
+	"collection":    {"editor", "title", "publisher", "year|date"},
+	"proceedings":   {"title", "year|date"},
+	"inbook":        {"author|editor", "title", "chapter|pages|booktitle", "publisher", "year|date"},
+	"booklet":       {"title"},
+	"manual":        {"title"},
+	"phdthesis":     {"author", "title", "school|institution", "year|date"},
+	"mastersthesis": {"author", "title", "school|institution", "year|date"},
+	"thesis":        {"author", "title", "type", "school|institution", "year|date"},
+	"misc":          {"title"},
+	"software":      {"title"},
+	"dataset":       {"title"},
+	"unpublished":   {"author", "title", "note"},
+	"patent":        {"author", "title", "number", "year|date"},
+	"online":        {"title", "url"},
+}
+
+// entryTypeAliases maps alternative BibTeX and BibLaTeX entry types to the
+// supported type that they are formatted as.
+var entryTypeAliases = map[string]string{
+	"conference": "inproceedings",
+	"report":     "techreport",
+	"mvbook":     "book",
+	"bookinbook": "inbook",
+	"electronic": "online",
+	"www":        "online",
+}
+
+var matchEntry = regexp.MustCompile(`(?m)^[ \t]*@[ \t]*([A-Za-z]+)[ \t]*[{(][ \t]*([^,\s]+)[ \t]*,`)
+
+// entryLines returns the line numbers at which each cite key in the BibTeX
+// source b is defined, in order of appearance.
+func entryLines(b []byte) map[string][]int {
+	out := make(map[string][]int)
+	for _, m := range matchEntry.FindAllSubmatchIndex(b, -1) {
+		key := string(b[m[4]:m[5]])
+		out[key] = append(out[key], bytes.Count(b[:m[4]], []byte("\n"))+1)
+	}
+	return out
 }
 
-func parseBibtex(bibs []string) map[template.HTML]*bibtex.BibEntry {
-	out := make(map[template.HTML]*bibtex.BibEntry)
+func parseBibtex(bibs []string) (map[template.HTML]*bibEntry, error) {
+	var ds diagnostics
+	out := make(map[template.HTML]*bibEntry)
 	for _, bib := range bibs {
-		f, err := os.Open(bib)
-		check(err)
-		b := new(bytes.Buffer)
-		_, err = io.Copy(b, f)
-		check(err)
-		elems, err := bibtex.Parse(b)
-		check(err)
+		b, err := os.ReadFile(bib)
+		if err != nil {
+			ds.add("", 0, "%v", err)
+			continue
+		}
+		elems, err := bibtex.Parse(bytes.NewReader(b))
+		if err != nil {
+			if perr, ok := err.(*bibtex.ErrParse); ok {
+				ds.add(bib, len(perr.Pos.Lines)+1, "%s", perr.Err)
+			} else {
+				ds.add(bib, 0, "%v", err)
+			}
+			continue
+		}
+		lines := entryLines(b)
 		for _, e := range elems.Entries {
-			if _, ok := out[template.HTML(e.CiteName)]; ok {
-				panic(e.CiteName)
+			entry := &bibEntry{BibEntry: e, File: bib}
+			if l := lines[e.CiteName]; len(l) > 0 {
+				entry.Line, lines[e.CiteName] = l[0], l[1:]
+			}
+			for k, v := range e.Fields {
+				e.Fields[strings.ToLower(k)] = v
+			}
+			if t, ok := entryTypeAliases[e.Type]; ok {
+				e.Type = t
+			}
+			key := template.HTML(e.CiteName)
+			if prev, ok := out[key]; ok {
+				ds.add(bib, entry.Line, "duplicate citation key %s (previously defined at %s:%d)", key, prev.File, prev.Line)
+				continue
 			}
-			out[template.HTML(e.CiteName)] = e
+			out[key] = entry
 		}
 	}
-	return out
+	return out, ds.err()
 }
 
-func underlineName(s string) string {
-	for _, name := range []string{"Tessum, C.W.", "C.W. Tessum", "Park, M", "M. Park",
-		"X. Yang", "Yang, X", "S. Wang", "Wang, S", "L. Guo", "Guo, L", "Q. Fatima", "Fatima, Q",
-		"Liu, J", "J. Liu", "X. Ran", "Ran, X", "Kazemi, A", "A. Kazemi"} {
-		s = strings.Replace(s, name, fmt.Sprintf("<u>%s</u>", name), -1)
+// checkCitations reports citations in the data file that are missing from
+// the bibliographies or that cannot be formatted.
+func checkCitations(d *Data, citations map[template.HTML]*bibEntry) error {
+	var ds diagnostics
+	checked := make(map[template.HTML]bool)
+	for _, s := range d.Sections {
+		for _, key := range s.Citations {
+			e, ok := citations[key]
+			if !ok {
+				ds.add(d.file, s.citationLines[key], "section %s: citation key %s not found in bibliographies", s.ID, key)
+				continue
+			}
+			if checked[key] {
+				continue
+			}
+			checked[key] = true
+			required, ok := requiredFields[e.Type]
+			if !ok {
+				ds.add(e.File, e.Line, "%s: unsupported entry type %s", key, e.Type)
+				continue
+			}
+			for _, f := range required {
+				if !e.hasAny(strings.Split(f, "|")...) {
+					ds.add(e.File, e.Line, "%s: missing %s field", key, strings.Replace(f, "|", " or ", -1))
+				}
+			}
+			if err := e.checkLinks(); err != nil {
+				ds.add(e.File, e.Line, "%s: %v", key, err)
+			}
+			authors := parseNames(e.rawField("author"))
+			for _, f := range []string{"corresponding", "equalcontrib"} {
+				for _, n := range parseNames(e.rawField(f)) {
+					if n.Last == "" {
+						ds.add(e.File, e.Line, "%s: blank %s name", key, f)
+					} else if !slices.ContainsFunc(authors, func(a name) bool { return sameName(n, a) }) {
+						ds.add(e.File, e.Line, "%s: %s name %s is not an author", key, f, n.Last)
+					}
+				}
+			}
+		}
 	}
-	return s
+	return ds.err()
+}
+
+// A citationGroup is a run of citations from the same year. Groups are
+// numbered from Start downwards so that a reversed list continues across
+// groups.
+type citationGroup struct {
+	Year      string
+	Start     int
+	Citations []template.HTML
 }
 
-func formatCitationFunc(citations map[template.HTML]*bibtex.BibEntry) func(template.HTML) (template.HTML, error) {
-	return func(key template.HTML) (template.HTML, error) {
-		elem, ok := citations[key]
-		if !ok {
-			return "", fmt.Errorf("invalid citation key %s", key)
+// groupByYear splits keys into runs of consecutive citations with the same
+// year, which are single groups if the section is sorted by date.
+func groupByYear(citations map[template.HTML]*bibEntry, keys []template.HTML) []citationGroup {
+	var groups []citationGroup
+	n := len(keys)
+	for _, k := range keys {
+		year := "Undated"
+		if e, ok := citations[k]; ok && e.year() != "" {
+			year = e.year()
 		}
-		switch elem.Type {
-		case "article":
-			return template.HTML(underlineName(parseArticle(elem))), nil
-		case "inproceedings":
-			return template.HTML(underlineName(parseProceedings(elem))), nil
-		case "techreport":
-			return template.HTML(underlineName(parseReport(elem))), nil
-		case "incollection":
-			return template.HTML(underlineName(parseCollection(elem))), nil
-		default:
-			return "", fmt.Errorf("invalid citation type %s", elem.Type)
+		if len(groups) == 0 || groups[len(groups)-1].Year != year {
+			groups = append(groups, citationGroup{Year: year, Start: n})
 		}
+		g := &groups[len(groups)-1]
+		g.Citations = append(g.Citations, k)
+		n--
 	}
+	return groups
 }
 
 var matchDots *regexp.Regexp
@@ -442,109 +430,203 @@ func init() {
 	matchDots = regexp.MustCompile(`[\.]{2,}`)
 }
 
-func parseArticle(elem *bibtex.BibEntry) string {
-	for k, v := range elem.Fields {
-		elem.Fields[strings.ToLower(k)] = v
+func parseArticle(elem *bibEntry) string {
+	title := parseTitle(elem.field("title"))
+	authors := parseAuthors(elem.names("author"))
+	year := parseYear(elem.year())
+	journal := ""
+	if elem.has("journal") {
+		journal = parsePublication(elem.field("journal"))
 	}
-	title := parseTitle(elem.Fields["title"].String())
-	authors := parseAuthors(elem.Fields["author"].String())
-	year := parseYear(elem.Fields["year"].String())
-	journal := parsePublication(elem.Fields["journal"].String())
 	volume := ""
-	if elem.Fields["volume"] != nil {
-		volume = parseVolume(elem.Fields["volume"].String())
+	if elem.has("volume") {
+		volume = parseVolume(elem.field("volume"))
 	}
 	issue := ""
-	if elem.Fields["number"] != nil {
-		issue = parseIssue(elem.Fields["number"].String())
+	if elem.has("number") {
+		issue = parseIssue(elem.field("number"))
 	}
 	pages := ""
-	if elem.Fields["pages"] != nil {
-		pages = parsePages(elem.Fields["pages"].String())
+	if elem.has("pages") {
+		pages = parsePages(elem.field("pages"))
 	}
-	url := parseURL(elem.Fields["url"].String())
 	s := authors
 	if year != "" {
-		s = fmt.Sprintf("%s (%s)", s, year)
-	} else if !(s[len(s)-1:] == "." || s[len(s)-2:] == ".*") {
-		s = fmt.Sprintf("%s.", s)
+		s = joinNonEmpty(" ", s, "("+year+")")
+	} else if s != "" && !strings.HasSuffix(s, ".") && !strings.HasSuffix(s, ".*") {
+		s += "."
 	}
 	if title != "" {
-		s = fmt.Sprintf("%s %s.", s, title)
+		s = joinNonEmpty(" ", s, title+".")
 	}
 	if journal != "" {
-		s = fmt.Sprintf("%s %s.", s, journal)
-	}
-	if volume != "" {
-		s += " " + volume
+		s = joinNonEmpty(" ", s, journal+".")
 	}
-	if issue != "" {
-		s = fmt.Sprintf("%s:%s", s, issue)
-	}
-	if pages != "" {
-		if url != "" {
-			s += fmt.Sprintf(" <a href=%s>%s</a>.", url, pages)
-		} else {
-			s += " " + pages + "."
-		}
-	} else if url != "" {
-		s += fmt.Sprintf(" <a href=%s>%s</a>.", url, url)
+	s = joinNonEmpty(" ", s, joinNonEmpty(":", volume, issue))
+	if link := elem.link(pages); link != "" {
+		s = joinNonEmpty(" ", s, link+".")
 	} else {
 		s += "."
 	}
 	return matchDots.ReplaceAllString(s, ".")
 }
 
-func parseProceedings(elem *bibtex.BibEntry) string {
-	title := parseTitle(elem.Fields["title"].String())
-	authors := parseAuthors(elem.Fields["author"].String())
-	year := parseYear(elem.Fields["year"].String())
-	institution := parseBookTitle(elem.Fields["booktitle"].String())
-	location := parseLocation(elem.Fields["address"].String())
-	s := fmt.Sprintf("%s (%s) %s. Presented at %s, %s.", authors, year, title, institution, location)
-	return s
+func parseProceedings(elem *bibEntry) string {
+	title := parseTitle(elem.field("title"))
+	authors := parseAuthors(elem.names("author"))
+	year := parseYear(elem.year())
+	institution := parseBookTitle(elem.field("booktitle"))
+	location := parseLocation(elem.field("address"))
+	presented := joinNonEmpty(", ", institution, location)
+	if presented != "" {
+		presented = "Presented at " + presented
+	}
+	s := authors
+	if year != "" {
+		s = joinNonEmpty(" ", s, "("+year+")")
+	}
+	return joinNonEmpty(" ", s, joinNonEmpty(". ", title, presented)) + "."
 }
 
-func parseReport(elem *bibtex.BibEntry) string {
-	title := parseTitle(elem.Fields["title"].String())
-	authors := parseAuthors(elem.Fields["author"].String())
-	year := parseYear(elem.Fields["year"].String())
-	institution := parseBookTitle(elem.Fields["institution"].String())
-	location := parseLocation(elem.Fields["address"].String())
+func parseReport(elem *bibEntry) string {
+	title := parseTitle(elem.field("title"))
+	authors := parseAuthors(elem.names("author"))
+	institution := parseBookTitle(elem.field("institution"))
+	location := parseLocation(elem.field("address"))
 	pages := ""
-	if elem.Fields["pages"] != nil {
-		pages = parsePages(elem.Fields["pages"].String())
+	if elem.has("pages") {
+		pages = parsePages(elem.field("pages"))
 	}
-	url := ""
-	if elem.Fields["url"] != nil {
-		url = parseURL(elem.Fields["url"].String())
+	return formatEntry(authors, parseYear(elem.year()), fmt.Sprintf("\"%s\"", title),
+		joinNonEmpty(": ", "tech. rep.", institution), location, elem.link(pages))
+}
+
+func parseCollection(elem *bibEntry) string {
+	title := parseTitle(elem.field("title"))
+	authors := parseAuthors(elem.names("author"))
+	book := ""
+	if elem.has("booktitle") {
+		book = "in <i>" + parseBookTitle(elem.field("booktitle")) + "</i>"
 	}
-	s := fmt.Sprintf("%s (%s) \"%s\", tech. rep.: %s, %s", authors, year, title, institution, location)
-	if pages != "" {
-		if url != "" {
-			s += fmt.Sprintf(", <a href=%s>%s</a>.", url, pages)
-		} else {
-			s += ", " + pages + "."
+	eds := ""
+	if elem.has("editor") {
+		eds = "ed. by " + removeBrackets(elem.field("editor"))
+	}
+	pub := removeBrackets(elem.field("publisher"))
+	pages := parsePages(elem.field("pages"))
+	return formatEntry(authors, parseYear(elem.year()), fmt.Sprintf("\"%s\"", title), book, eds, pub, elem.link(pages))
+}
+
+func parseBook(elem *bibEntry) string {
+	authors := parseAuthors(elem.names("author"))
+	if authors == "" {
+		authors = parseAuthors(elem.names("editor")) + ", ed."
+	}
+	title := "<i>" + parseTitle(elem.field("title")) + "</i>"
+	volume := ""
+	if elem.has("volume") {
+		volume = "vol. " + elem.field("volume")
+	}
+	chapter := ""
+	if elem.has("chapter") {
+		chapter = "ch. " + elem.field("chapter")
+	}
+	pages := ""
+	if elem.has("pages") {
+		pages = "pp. " + parsePages(elem.field("pages"))
+	}
+	pub := elem.field("publisher")
+	for _, f := range []string{"organization", "institution", "howpublished"} {
+		if pub == "" {
+			pub = elem.field(f)
 		}
-	} else if url != "" {
-		s += fmt.Sprintf(", <a href=%s>%s</a>.", url, url)
-	} else {
-		s += "."
 	}
-	return s
+	return formatEntry(authors, elem.year(), title, editionLabel(elem.field("edition")), volume, chapter, pages,
+		pub, parseLocation(elem.field("address")), elem.link(""))
 }
 
-func parseCollection(elem *bibtex.BibEntry) string {
-	title := parseTitle(elem.Fields["title"].String())
-	authors := parseAuthors(elem.Fields["author"].String())
-	year := parseYear(elem.Fields["year"].String())
-	book := parseBookTitle(elem.Fields["booktitle"].String())
-	eds := removeBrackets(elem.Fields["editor"].String())
-	pub := removeBrackets(elem.Fields["publisher"].String())
-	pages := parsePages(elem.Fields["pages"].String())
-	url := parseURL(elem.Fields["url"].String())
-	s := fmt.Sprintf("%s (%s) \"%s\", in <i>%s</i>, ed. by %s, %s, %s, %s.", authors, year, title, book, eds, pub, pages, url)
-	return s
+func parseInBook(elem *bibEntry) string {
+	if !elem.has("booktitle") {
+		return parseBook(elem)
+	}
+	title := parseTitle(elem.field("title"))
+	authors := parseAuthors(elem.names("author"))
+	book := "in <i>" + parseBookTitle(elem.field("booktitle")) + "</i>"
+	eds := ""
+	if elem.has("editor") {
+		eds = "ed. by " + parseAuthors(elem.names("editor"))
+	}
+	pages := ""
+	if elem.has("pages") {
+		pages = "pp. " + parsePages(elem.field("pages"))
+	}
+	return formatEntry(authors, elem.year(), fmt.Sprintf("\"%s\"", title), book, eds, elem.field("publisher"), pages, elem.link(""))
+}
+
+func parseThesis(elem *bibEntry) string {
+	title := parseTitle(elem.field("title"))
+	authors := parseAuthors(elem.names("author"))
+	kind := elem.field("type")
+	if kind == "" {
+		kind = map[string]string{"phdthesis": "Ph.D. thesis", "mastersthesis": "M.S. thesis"}[elem.Type]
+	}
+	school := elem.field("school")
+	if school == "" {
+		school = elem.field("institution")
+	}
+	return formatEntry(authors, elem.year(), fmt.Sprintf("\"%s\"", title), joinNonEmpty(": ", kind, school),
+		parseLocation(elem.field("address")), elem.link(""))
+}
+
+func parseMisc(elem *bibEntry) string {
+	title := "<i>" + parseTitle(elem.field("title")) + "</i>"
+	authors := parseAuthors(elem.names("author"))
+	version := ""
+	if elem.has("version") {
+		version = "version " + elem.field("version")
+	}
+	return formatEntry(authors, elem.year(), title, version, elem.field("howpublished"), elem.field("publisher"),
+		elem.field("organization"), elem.field("note"), elem.link(""))
+}
+
+func parseUnpublished(elem *bibEntry) string {
+	title := parseTitle(elem.field("title"))
+	authors := parseAuthors(elem.names("author"))
+	return formatEntry(authors, elem.year(), fmt.Sprintf("\"%s\"", title), elem.field("note"), elem.link(""))
+}
+
+func parsePatent(elem *bibEntry) string {
+	title := parseTitle(elem.field("title"))
+	authors := parseAuthors(elem.names("author"))
+	kind := elem.field("type")
+	if kind == "" {
+		kind = "Patent"
+	}
+	return formatEntry(authors, elem.year(), fmt.Sprintf("\"%s\"", title), kind+" "+elem.field("number"), elem.link(""))
+}
+
+func parseOnline(elem *bibEntry) string {
+	title := parseTitle(elem.field("title"))
+	authors := parseAuthors(elem.names("author"))
+	if authors == "" {
+		authors = elem.field("organization")
+	}
+	accessed := ""
+	if elem.has("urldate") {
+		accessed = "accessed " + formatDate(elem.field("urldate"))
+	}
+	return formatEntry(authors, elem.year(), fmt.Sprintf("\"%s\"", title), elem.link(""), accessed)
+}
+
+// formatEntry formats an entry as its authors and year followed by the
+// non-empty parts.
+func formatEntry(authors, year string, parts ...string) string {
+	s := authors
+	if year != "" {
+		s = strings.TrimSpace(fmt.Sprintf("%s (%s)", s, year))
+	}
+	s = strings.TrimSpace(s + " " + joinNonEmpty(", ", parts...))
+	return matchDots.ReplaceAllString(s+".", ".")
 }
 
 func removeBrackets(s string) string {
@@ -559,60 +641,52 @@ func parseTitle(t string) string {
 	return strings.TrimRight(strings.TrimLeft(t, "{{"), "}}")
 }
 
-func parseAuthors(a string) string {
-	a = strings.Replace(a, `{\"{u}}`, "ü", -1)
-	as := strings.Split(a, " and ")
+func parseAuthors(names []name) string {
+	names, etAl := trimOthers(names)
 	var o string
-	for i, a := range as {
-		o += parseName(i, len(as), a)
+	for i, n := range names {
+		if etAl {
+			o += formatName(i, len(names)+1, n)
+		} else {
+			o += formatName(i, len(names), n)
+		}
+	}
+	if etAl {
+		o += " et al."
 	}
 	return o
 }
 
-func parseName(i, n int, a string) string {
-	corresponding := strings.Contains(a, "*")
-	a = strings.Replace(a, "*", "", -1)
-	a = strings.TrimLeft(strings.TrimRight(a, "}"), "{")
-	names := strings.Split(a, " ")
-	if !strings.Contains(names[0], ",") {
-		nn := len(names) - 1
-		names = append(names[nn:nn+1], names[0:nn]...)
-	}
-	family := strings.TrimRight(strings.TrimSpace(names[0]), ",")
-	given := strings.ToUpper(string(names[1][0])) + "."
-	given = strings.Replace(given, "~", " ", -1)
-	var middle string
-	if len(names) == 3 {
-		middle = strings.TrimRight(strings.TrimSpace(string(names[2][0])), ".") + "."
-	}
-	if i == 0 {
-		s := family + ", " + given
-		if len(names) == 3 {
-			s += middle
+func formatName(i, n int, a name) string {
+	var s string
+	switch {
+	case a.Corporate:
+		s = a.Last
+	case i == 0:
+		s = a.family()
+		if a.Initials != "" {
+			s += ", " + a.Initials
 		}
-		if corresponding {
-			s += "*"
+		if a.Jr != "" {
+			s += ", " + a.Jr
 		}
+	default:
+		s = strings.TrimSpace(a.Initials + " " + a.family())
+		if a.Jr != "" {
+			s += " " + a.Jr
+		}
+	}
+	s = markName(s, a)
+	if i == 0 {
 		if n == 1 {
 			return s
 		}
 		return s + ","
 	}
-	s := " " + given
-	if len(names) == 3 {
-		s += middle
-	}
-	s += " " + family
 	if i == n-1 {
-		if corresponding {
-			s += "*"
-		}
-		return " and " + s
+		return " and  " + s
 	}
-	if corresponding {
-		s += "*"
-	}
-	return s + ","
+	return " " + s + ","
 }
 
 func parsePublication(p string) string {
@@ -635,14 +709,11 @@ func parseIssue(i string) string {
 
 func parsePages(p string) string {
 	p = strings.TrimRight(strings.TrimLeft(p, "{"), "}")
-	p = strings.Replace(p, "--", "–", -1)
 	return p
 }
 
 func parseURL(u string) string {
-	u = strings.TrimSpace(strings.TrimRight(strings.TrimLeft(u, "{"), "}"))
-	u = strings.Replace(u, `{\_}`, "_", -1)
-	return u
+	return strings.TrimSpace(u)
 }
 
 func parseBookTitle(t string) string {
@@ -652,16 +723,18 @@ func parseBookTitle(t string) string {
 
 func parseLocation(t string) string {
 	t = strings.TrimRight(strings.TrimLeft(t, "{"), "}")
-	t = strings.Replace(t, `{\~{a}}`, "ã", -1)
 	return t
 }
 
-func printPDF(cv []byte, filename string) {
+// printPDF prints the HTML document cv to a PDF with headless Chrome,
+// with the given page setup and with the given header and footer
+// templates if either is set.
+func printPDF(cv []byte, p PrintSettings, header, footer, filename string) error {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
-		_, err := w.Write(bytes.TrimSpace(cv))
-		check(err)
+		w.Write(bytes.TrimSpace(cv))
 	}))
+	defer ts.Close()
 
 	opts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.NoSandbox)
 
@@ -672,28 +745,33 @@ func printPDF(cv []byte, filename string) {
 	defer cancel()
 
 	pdfPrint := chromedp.ActionFunc(func(ctx context.Context) error {
-		pdf := page.PrintToPDF()
-		pdf = pdf.WithMarginTop(1)
-		pdf = pdf.WithMarginBottom(1)
+		width, height := paperSizes[p.Paper][0], paperSizes[p.Paper][1]
+		pdf := page.PrintToPDF().
+			WithPaperWidth(width / 72).
+			WithPaperHeight(height / 72).
+			WithLandscape(p.Landscape).
+			WithScale(p.Scale).
+			WithMarginTop(p.Margins.Top).
+			WithMarginBottom(p.Margins.Bottom).
+			WithMarginLeft(p.Margins.Left).
+			WithMarginRight(p.Margins.Right).
+			WithGenerateTaggedPDF(true).
+			WithGenerateDocumentOutline(true)
+		if header != "" || footer != "" {
+			// An empty template would show Chrome's default.
+			pdf = pdf.WithDisplayHeaderFooter(true).
+				WithHeaderTemplate(cmp.Or(header, "<span></span>")).
+				WithFooterTemplate(cmp.Or(footer, "<span></span>"))
+		}
 		data, _, err := pdf.Do(ctx)
-		check(err)
-
-		o, err := os.Create(filename)
-		check(err)
-		_, err = o.Write(data)
-		check(err)
-		o.Close()
-		return nil
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filename, data, 0644)
 	})
 
-	check(chromedp.Run(ctx,
+	return chromedp.Run(ctx,
 		chromedp.Navigate(ts.URL),
 		pdfPrint,
-	))
-}
-
-func check(err error) {
-	if err != nil {
-		panic(err)
-	}
+	)
 }
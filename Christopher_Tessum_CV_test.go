@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"html/template"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testData loads a data file with the given contents, which refers to a
+// bibliography test.bib with the contents bib.
+func testData(t *testing.T, data, bib string) (*Data, map[template.HTML]*bibEntry, error) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, contents := range map[string]string{"test.yaml": data, "test.bib": bib} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return load(filepath.Join(dir, "test.yaml"))
+}
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// testRenderings returns the documents of testdata/sample.yaml, built at
+// a fixed date.
+func testRenderings(t *testing.T) []*rendering {
+	t.Helper()
+	data, citations, err := load(filepath.Join("testdata", "sample.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []*rendering
+	for _, doc := range data.Documents {
+		r, err := data.rendering(doc, citations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Date = time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+		out = append(out, r)
+	}
+	return out
+}
+
+// checkGolden compares got with the golden file testdata/name, or
+// rewrites the file with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from %s; rerun with -update and review the difference:\n%s", name, golden, got)
+	}
+}
+
+// export writes r with the given writer to a temporary file and returns
+// its contents.
+func export(t *testing.T, r *rendering, write func(*rendering, string) error) []byte {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), r.ID)
+	if err := write(r, filename); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// testBib parses the BibTeX entries in src.
+func testBib(t *testing.T, src string) map[template.HTML]*bibEntry {
+	t.Helper()
+	f := filepath.Join(t.TempDir(), "test.bib")
+	if err := os.WriteFile(f, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	citations, err := parseBibtex([]string{f})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return citations
+}
+
+func TestDefaultStyleSparseEntries(t *testing.T) {
+	citations := testBib(t, `
+@article{full,
+  author = {Tessum, Christopher W. and Hill, Jason D. and Marshall, Julian D.},
+  title = {Life cycle air quality impacts},
+  journal = {Proc. Natl. Acad. Sci.},
+  volume = {111}, number = {52}, pages = {18490--18495}, year = {2014},
+}
+@article{bare, title = {Only a title}}
+@article{untitled, journal = {Nature}}
+@article{issueonly, author = {Hill, Jason D.}, title = {T}, journal = {J}, number = {3}, year = {2020}}
+@article{dated, author = {Hill, Jason D.}, title = {T}, journal = {J}, date = {2021-05}}
+@inproceedings{proc, author = {Hill, Jason D.}, title = {A talk}, year = {2019}}
+@inproceedings{proconly, title = {A talk}, address = {Seattle, WA}}
+@incollection{coll, author = {Hill, Jason D.}, title = {A chapter}, booktitle = {A book}, year = {2018}}
+@techreport{rep, title = {A report}, year = {2010}}
+`)
+	for _, tt := range []struct{ key, want string }{
+		{"full", "Tessum, C.W., J.D. Hill, and  J.D. Marshall (2014) Life cycle air quality impacts. <i>Proc. Natl. Acad. Sci</i>. <strong>111</strong>:52 18490–18495."},
+		{"bare", "Only a title."},
+		{"untitled", "<i>Nature</i>."},
+		{"issueonly", "Hill, J.D. (2020) T. <i>J</i>. 3."},
+		{"dated", "Hill, J.D. (2021) T. <i>J</i>."},
+		{"proc", "Hill, J.D. (2019) A talk."},
+		{"proconly", "A talk. Presented at Seattle, WA."},
+		{"coll", `Hill, J.D. (2018) "A chapter", in <i>A book</i>.`},
+		{"rep", `(2010) "A report", tech. rep.`},
+	} {
+		got, err := defaultStyle{}.Format(citations[template.HTML(tt.key)])
+		if err != nil {
+			t.Errorf("%s: %v", tt.key, err)
+		} else if got != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestGroupByYear(t *testing.T) {
+	citations := testBib(t, `
+@article{a, title = {A}, year = {2021}}
+@article{b, title = {B}, year = {2021}}
+@article{c, title = {C}, date = {2020-03}}
+@article{d, title = {D}}
+@article{e, title = {E}, year = {2021}}
+`)
+	got := groupByYear(citations, []template.HTML{"a", "b", "c", "d", "missing", "e"})
+	want := []citationGroup{
+		{Year: "2021", Start: 6, Citations: []template.HTML{"a", "b"}},
+		{Year: "2020", Start: 4, Citations: []template.HTML{"c"}},
+		{Year: "Undated", Start: 3, Citations: []template.HTML{"d", "missing"}},
+		{Year: "2021", Start: 1, Citations: []template.HTML{"e"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+	if got := groupByYear(citations, nil); got != nil {
+		t.Errorf("no keys: got %+v, want no groups", got)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	for _, r := range testRenderings(t) {
+		b := export(t, r, func(r *rendering, filename string) error {
+			return writeHTML(r, defaultTemplateFile, filename)
+		})
+		checkGolden(t, r.ID+".html", b)
+		for _, tag := range []string{"<link", "<script"} {
+			if bytes.Contains(b, []byte(tag)) {
+				t.Errorf("%s.html contains %s", r.ID, tag)
+			}
+		}
+	}
+}
+
+// TestHTMLContact checks that the contact line of the HTML template shows
+// only the owner's details that are given.
+func TestHTMLContact(t *testing.T) {
+	r := testRenderings(t)[0]
+	for _, tt := range []struct {
+		owner Owner
+		want  string
+	}{
+		{Owner{Name: "X"}, ""},
+		{Owner{Name: "X", Email: "x@example.com"}, "x@example.com"},
+		{Owner{Name: "X", ORCID: "0000-0001"}, "<a href=https://orcid.org/0000-0001>ORCID: 0000-0001</a>"},
+		{Owner{Name: "X", Website: "https://x.org/"}, "<a href=https://x.org/>https://x.org/</a>"},
+		{Owner{Name: "X", Email: "x@example.com", Website: "https://x.org/"}, "x@example.com\r\n        <br> <a href=https://x.org/>https://x.org/</a>"},
+	} {
+		r.Owner = tt.owner
+		b, err := renderHTML(r, defaultTemplateFile, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, contact, _ := strings.Cut(string(b), `<div class="col-md-7 text-right">`)
+		contact, _, _ = strings.Cut(contact, "</div>")
+		if got := strings.TrimSpace(contact); got != tt.want {
+			t.Errorf("%+v: contact %q, want %q", tt.owner, got, tt.want)
+		}
+	}
+}
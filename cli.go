@@ -0,0 +1,286 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+const usage = `usage: cv <command> [flags]
+
+Commands:
+  build      render documents to PDF and other formats
+  list-docs  list the documents defined in the data file
+  check      validate the data file, bibliographies and template
+  import     create a data file from a JSON Resume
+
+Run 'cv <command> -h' for the flags of each command.
+`
+
+const (
+	defaultDataFile     = "Christopher_Tessum_CV.yaml"
+	defaultTemplateFile = "Christopher_Tessum_CV_template.html"
+)
+
+// An outputFormat is a format that the build command can write, with the
+// extension that replaces that of each document's output file.
+type outputFormat struct{ name, ext string }
+
+var outputFormats = []outputFormat{
+	{"pdf", ".pdf"},
+	{"html", ".html"},
+	{"tex", ".tex"},
+	{"docx", ".docx"},
+	{"md", ".md"},
+	{"txt", ".txt"},
+	{"json", ".json"},
+}
+
+// errUsage indicates that the command line was invalid and the usage
+// has already been printed.
+var errUsage = errors.New("usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command given by args and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	var cmd func([]string, io.Writer, io.Writer) error
+	switch args[0] {
+	case "build":
+		cmd = buildCmd
+	case "list-docs":
+		cmd = listDocsCmd
+	case "check":
+		cmd = checkCmd
+	case "import":
+		cmd = importCmd
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "cv: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+	if err := cmd(args[1:], stdout, stderr); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		fmt.Fprintf(stderr, "cv %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: cv %s [flags]\n\nFlags:\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected argument %q\n", fs.Arg(0))
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// load reads the data file and its bibliographies, reporting every problem
+// found in any of them together.
+func load(dataFile string) (*Data, map[template.HTML]*bibEntry, error) {
+	data, err := loadData(dataFile)
+	if data == nil {
+		return nil, nil, err
+	}
+	var ds diagnostics
+	ds.merge(err)
+	citations, err := parseBibtex(data.Bibliographies)
+	ds.merge(err)
+	ds.merge(data.expand(citations))
+	ds.merge(checkCitations(data, citations))
+	return data, citations, ds.err()
+}
+
+func buildCmd(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("build", stderr)
+	dataFile := fs.String("data", defaultDataFile, "CV data `file`")
+	templateFile := fs.String("template", defaultTemplateFile, "HTML template `file`")
+	docs := fs.String("doc", "", "comma-separated `ids` of the documents to build (default all)")
+	outDir := fs.String("out", ".", "output `directory`")
+	formats := fs.String("format", "pdf", "comma-separated output `formats`: "+formatNames())
+	renderer := fs.String("renderer", "chrome", "PDF `renderer`: chrome (print the HTML template with headless Chrome) or go (lay out the PDF directly, without a browser)")
+	fonts := fs.String("fonts", "", "`directory` of TrueType fonts regular.ttf, bold.ttf, italic.ttf and bolditalic.ttf for the go renderer (default the built-in Times)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *renderer != "chrome" && *renderer != "go" {
+		fmt.Fprintf(fs.Output(), "unknown renderer %q (want chrome or go)\n", *renderer)
+		fs.Usage()
+		return errUsage
+	}
+	var selectedFormats []string
+	for _, f := range strings.Split(*formats, ",") {
+		f = strings.TrimSpace(f)
+		if !slices.ContainsFunc(outputFormats, func(o outputFormat) bool { return o.name == f }) {
+			fmt.Fprintf(fs.Output(), "unknown format %q (want %s)\n", f, formatNames())
+			fs.Usage()
+			return errUsage
+		}
+		selectedFormats = append(selectedFormats, f)
+	}
+
+	data, citations, err := load(*dataFile)
+	if err != nil {
+		return err
+	}
+	selected := data.Documents
+	if *docs != "" {
+		selected = nil
+		for _, id := range strings.Split(*docs, ",") {
+			doc, err := data.Document(strings.TrimSpace(id))
+			if err != nil {
+				return err
+			}
+			selected = append(selected, doc)
+		}
+	}
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+	for _, doc := range selected {
+		r, err := data.rendering(doc, citations)
+		if err != nil {
+			return err
+		}
+		for _, f := range outputFormats {
+			if !slices.Contains(selectedFormats, f.name) {
+				continue
+			}
+			filename := filepath.Join(*outDir, strings.TrimSuffix(doc.Output, filepath.Ext(doc.Output))+f.ext)
+			switch {
+			case f.name == "html":
+				err = writeHTML(r, *templateFile, filename)
+			case f.name == "tex":
+				err = writeTeX(r, filename)
+			case f.name == "docx":
+				err = writeDOCX(r, filename)
+			case f.name == "md":
+				err = writeMarkdown(r, filename)
+			case f.name == "txt":
+				err = writeText(r, filename)
+			case f.name == "json":
+				err = writeJSONResume(r, filename)
+			case *renderer == "go":
+				err = writePDF(r, *fonts, filename)
+			default:
+				err = render(r, *templateFile, filename)
+			}
+			if err != nil {
+				return fmt.Errorf("document %s: %v", doc.ID, err)
+			}
+			fmt.Fprintf(stdout, "wrote %s\n", filename)
+		}
+	}
+	return nil
+}
+
+// formatNames returns the names of the output formats for messages.
+func formatNames() string {
+	var names []string
+	for _, f := range outputFormats {
+		names = append(names, f.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func listDocsCmd(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("list-docs", stderr)
+	dataFile := fs.String("data", defaultDataFile, "CV data `file`")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	data, err := loadData(*dataFile)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tOUTPUT\tSECTIONS")
+	for _, doc := range data.Documents {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", doc.ID, doc.Output, len(doc.Sections))
+	}
+	return w.Flush()
+}
+
+func checkCmd(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("check", stderr)
+	dataFile := fs.String("data", defaultDataFile, "CV data `file`")
+	templateFile := fs.String("template", defaultTemplateFile, "HTML template `file`")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	data, citations, err := load(*dataFile)
+	if err != nil {
+		return err
+	}
+	for _, doc := range data.Documents {
+		r, err := data.rendering(doc, citations)
+		if err != nil {
+			return err
+		}
+		for _, standalone := range []bool{false, true} {
+			if _, err := renderHTML(r, *templateFile, standalone); err != nil {
+				return fmt.Errorf("document %s: %v", doc.ID, err)
+			}
+		}
+	}
+	fmt.Fprintf(stdout, "%s: %d documents ok\n", *dataFile, len(data.Documents))
+	return nil
+}
+
+func importCmd(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("import", stderr)
+	in := fs.String("in", "", "JSON Resume `file` to import")
+	out := fs.String("out", "", "data `file` to create, along with a bibliography of the same name for any publications (default the name followed by _CV.yaml)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *in == "" {
+		fmt.Fprintln(fs.Output(), "missing -in")
+		fs.Usage()
+		return errUsage
+	}
+	written, err := importJSONResume(*in, *out)
+	for _, f := range written {
+		fmt.Fprintf(stdout, "wrote %s\n", f)
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	sample := filepath.Join("testdata", "sample.yaml")
+	invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("owner: {name: X}\nsections: [{id: s, name: S, citations: [missing]}]\ndocuments: [{id: cv, output: cv.pdf, sections: [s, nope]}]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		args           []string
+		code           int
+		stdout, stderr string // substrings of the output
+	}{
+		{nil, 2, "", "usage: cv <command>"},
+		{[]string{"help"}, 0, "usage: cv <command>", ""},
+		{[]string{"frobnicate"}, 2, "", `cv: unknown command "frobnicate"`},
+		{[]string{"check", "-data", sample}, 0, sample + ": 2 documents ok", ""},
+		{[]string{"check", "-data", invalid}, 1, "", "cv check: 3 problems found"},
+		{[]string{"check", "-data", "missing.yaml"}, 1, "", "cv check: open missing.yaml"},
+		{[]string{"check", "-h"}, 0, "", "usage: cv check [flags]"},
+		{[]string{"check", "extra"}, 2, "", `unexpected argument "extra"`},
+		{[]string{"check", "-nope"}, 2, "", "flag provided but not defined: -nope"},
+		{[]string{"list-docs", "-data", sample}, 0, "cv     sample.pdf        4\nshort  sample_short.pdf  2", ""},
+		{[]string{"build", "-data", sample, "-format", "md,rtf"}, 2, "", `unknown format "rtf"`},
+		{[]string{"build", "-data", sample, "-renderer", "latex"}, 2, "", `unknown renderer "latex"`},
+		{[]string{"build", "-data", sample, "-doc", "nope", "-format", "md"}, 1, "", "cv build: no document with id nope"},
+		{[]string{"import"}, 2, "", "missing -in"},
+	} {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, &stdout, &stderr)
+		if code != tt.code || !strings.Contains(stdout.String(), tt.stdout) || !strings.Contains(stderr.String(), tt.stderr) {
+			t.Errorf("run(%q) = %d, stdout:\n%s\nstderr:\n%s\nwant %d, %q and %q", tt.args, code, &stdout, &stderr, tt.code, tt.stdout, tt.stderr)
+		}
+	}
+}
+
+func TestRunBuild(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	var stdout, stderr bytes.Buffer
+	args := []string{"build", "-data", filepath.Join("testdata", "sample.yaml"), "-doc", "short", "-format", "md, txt", "-out", out}
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("run(%q) = %d: %s", args, code, &stderr)
+	}
+	want := []string{"sample_short.md", "sample_short.txt"}
+	if got := stdout.String(); got != "wrote "+filepath.Join(out, want[0])+"\nwrote "+filepath.Join(out, want[1])+"\n" {
+		t.Errorf("stdout:\n%s", got)
+	}
+	entries, err := os.ReadDir(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,1292 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// cslStyle is a citation style written in the Citation Style Language
+// (https://citationstyles.org), read from a .csl file. Entries are
+// rendered with the style's bibliography layout. Only what affects a
+// single bibliography entry is supported: sorting, disambiguation and
+// citation layouts are ignored, and terms come from the style's English
+// locales with built-in en-US defaults.
+type cslStyle struct {
+	macros  map[string]*cslNode
+	layout  *cslNode
+	options map[string]string // inheritable name options
+	terms   map[string]cslTerm
+	dates   map[string]*cslNode // localized date formats
+
+	punctuationInQuote bool
+}
+
+// A cslNode is an element of a CSL style file.
+type cslNode struct {
+	Name     string
+	Attrs    map[string]string
+	Text     string
+	Children []*cslNode
+}
+
+func (n *cslNode) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	n.Name = start.Name.Local
+	n.Attrs = make(map[string]string)
+	for _, a := range start.Attr {
+		n.Attrs[a.Name.Local] = a.Value
+	}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			c := new(cslNode)
+			if err := c.UnmarshalXML(d, t); err != nil {
+				return err
+			}
+			n.Children = append(n.Children, c)
+		case xml.CharData:
+			n.Text += string(t)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+func (n *cslNode) attr(name string) string {
+	if n == nil {
+		return ""
+	}
+	return n.Attrs[name]
+}
+
+func (n *cslNode) has(name string) bool {
+	if n == nil {
+		return false
+	}
+	_, ok := n.Attrs[name]
+	return ok
+}
+
+func (n *cslNode) child(name string) *cslNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// cslNameOptions are the name attributes that cs:style and
+// cs:bibliography pass down to cs:names and cs:name, mapped to the
+// attribute they set there.
+var cslNameOptions = map[string]string{
+	"and":                      "and",
+	"delimiter-precedes-et-al": "delimiter-precedes-et-al",
+	"delimiter-precedes-last":  "delimiter-precedes-last",
+	"et-al-min":                "et-al-min",
+	"et-al-use-first":          "et-al-use-first",
+	"et-al-use-last":           "et-al-use-last",
+	"initialize":               "initialize",
+	"initialize-with":          "initialize-with",
+	"name-as-sort-order":       "name-as-sort-order",
+	"sort-separator":           "sort-separator",
+	"name-form":                "form",
+	"name-delimiter":           "delimiter",
+	"names-delimiter":          "names-delimiter",
+}
+
+// loadCSL reads a CSL style file.
+func loadCSL(filename string) (*cslStyle, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var root cslNode
+	if err := xml.Unmarshal(b, &root); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if root.Name != "style" {
+		return nil, fmt.Errorf("%s: not a CSL style", filename)
+	}
+	if info := root.child("info"); info != nil {
+		for _, link := range info.Children {
+			if link.Name == "link" && link.attr("rel") == "independent-parent" {
+				return nil, fmt.Errorf("%s: dependent style; use its parent %s instead", filename, link.attr("href"))
+			}
+		}
+	}
+
+	s := &cslStyle{
+		macros:  make(map[string]*cslNode),
+		options: make(map[string]string),
+		terms:   make(map[string]cslTerm),
+		dates:   make(map[string]*cslNode),
+	}
+	s.addLocale(cslDefaultLocale)
+	for _, c := range root.Children {
+		switch c.Name {
+		case "macro":
+			s.macros[c.attr("name")] = c
+		case "locale":
+			if lang := c.attr("lang"); lang == "" || lang == "en" || strings.HasPrefix(lang, "en-US") {
+				s.addLocale(c)
+			}
+		}
+	}
+	bib := root.child("bibliography")
+	s.layout = bib.child("layout")
+	if s.layout == nil {
+		return nil, fmt.Errorf("%s: style has no bibliography layout", filename)
+	}
+	for _, n := range []*cslNode{&root, bib} {
+		for k, v := range n.Attrs {
+			if o, ok := cslNameOptions[k]; ok {
+				s.options[o] = v
+			}
+		}
+	}
+	if err := s.checkMacros(s.layout, 0); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return s, nil
+}
+
+// checkMacros reports references to undefined macros and macros that call
+// themselves.
+func (s *cslStyle) checkMacros(n *cslNode, depth int) error {
+	if depth > 50 {
+		return fmt.Errorf("macros nested too deeply")
+	}
+	if n.Name == "text" && n.has("macro") {
+		m, ok := s.macros[n.attr("macro")]
+		if !ok {
+			return fmt.Errorf("undefined macro %s", n.attr("macro"))
+		}
+		if err := s.checkMacros(m, depth+1); err != nil {
+			return err
+		}
+	}
+	for _, c := range n.Children {
+		if err := s.checkMacros(c, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A cslTerm is a localized term in its singular and plural forms.
+type cslTerm struct {
+	single, multiple string
+}
+
+func (s *cslStyle) addLocale(l *cslNode) {
+	for _, c := range l.Children {
+		switch c.Name {
+		case "style-options":
+			if c.has("punctuation-in-quote") {
+				s.punctuationInQuote = c.attr("punctuation-in-quote") == "true"
+			}
+		case "date":
+			s.dates[c.attr("form")] = c
+		case "terms":
+			for _, t := range c.Children {
+				form := t.attr("form")
+				if form == "" {
+					form = "long"
+				}
+				term := cslTerm{single: t.Text, multiple: t.Text}
+				if single := t.child("single"); single != nil {
+					term.single = single.Text
+					term.multiple = single.Text
+				}
+				if multiple := t.child("multiple"); multiple != nil {
+					term.multiple = multiple.Text
+				}
+				s.terms[t.attr("name")+"/"+form] = term
+			}
+		}
+	}
+}
+
+// term returns the named term as HTML, falling back to other forms as
+// described in the CSL specification.
+func (s *cslStyle) term(name, form string, plural bool) string {
+	forms := []string{"long"}
+	switch form {
+	case "short":
+		forms = []string{"short", "long"}
+	case "verb":
+		forms = []string{"verb", "long"}
+	case "verb-short":
+		forms = []string{"verb-short", "verb", "long"}
+	case "symbol":
+		forms = []string{"symbol", "short", "long"}
+	}
+	for _, f := range forms {
+		if t, ok := s.terms[name+"/"+f]; ok {
+			if plural {
+				return escapeHTML.Replace(t.multiple)
+			}
+			return escapeHTML.Replace(t.single)
+		}
+	}
+	return ""
+}
+
+func (s *cslStyle) ordinal(n int) string {
+	suffix := ""
+	if n%100 < 11 || n%100 > 13 {
+		suffix = s.term(fmt.Sprintf("ordinal-%02d", n%10), "long", false)
+	}
+	if suffix == "" {
+		suffix = s.term("ordinal", "long", false)
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+var cslDefaultLocale = mustParseCSL(`<locale xml:lang="en-US">
+  <style-options punctuation-in-quote="true"/>
+  <date form="text">
+    <date-part name="month" suffix=" "/>
+    <date-part name="day" suffix=", "/>
+    <date-part name="year"/>
+  </date>
+  <date form="numeric">
+    <date-part name="month" form="numeric" suffix="/"/>
+    <date-part name="day" suffix="/"/>
+    <date-part name="year"/>
+  </date>
+  <terms>
+    <term name="accessed">accessed</term>
+    <term name="and">and</term>
+    <term name="and others">and others</term>
+    <term name="anonymous">anonymous</term>
+    <term name="anonymous" form="short">anon.</term>
+    <term name="at">at</term>
+    <term name="available at">available at</term>
+    <term name="by">by</term>
+    <term name="circa">circa</term>
+    <term name="circa" form="short">c.</term>
+    <term name="cited">cited</term>
+    <term name="et-al">et al.</term>
+    <term name="forthcoming">forthcoming</term>
+    <term name="from">from</term>
+    <term name="ibid">ibid.</term>
+    <term name="in">in</term>
+    <term name="in press">in press</term>
+    <term name="internet">internet</term>
+    <term name="no date">no date</term>
+    <term name="no date" form="short">n.d.</term>
+    <term name="online">online</term>
+    <term name="presented at">presented at the</term>
+    <term name="retrieved">retrieved</term>
+    <term name="scale">scale</term>
+    <term name="version">version</term>
+    <term name="open-quote">“</term>
+    <term name="close-quote">”</term>
+    <term name="open-inner-quote">‘</term>
+    <term name="close-inner-quote">’</term>
+    <term name="page-range-delimiter">–</term>
+    <term name="ordinal">th</term>
+    <term name="ordinal-01">st</term>
+    <term name="ordinal-02">nd</term>
+    <term name="ordinal-03">rd</term>
+    <term name="book"><single>book</single><multiple>books</multiple></term>
+    <term name="chapter"><single>chapter</single><multiple>chapters</multiple></term>
+    <term name="chapter" form="short"><single>chap.</single><multiple>chaps.</multiple></term>
+    <term name="edition"><single>edition</single><multiple>editions</multiple></term>
+    <term name="edition" form="short">ed.</term>
+    <term name="issue"><single>issue</single><multiple>issues</multiple></term>
+    <term name="issue" form="short"><single>no.</single><multiple>nos.</multiple></term>
+    <term name="number"><single>number</single><multiple>numbers</multiple></term>
+    <term name="number" form="short"><single>no.</single><multiple>nos.</multiple></term>
+    <term name="page"><single>page</single><multiple>pages</multiple></term>
+    <term name="page" form="short"><single>p.</single><multiple>pp.</multiple></term>
+    <term name="volume"><single>volume</single><multiple>volumes</multiple></term>
+    <term name="volume" form="short"><single>vol.</single><multiple>vols.</multiple></term>
+    <term name="editor"><single>editor</single><multiple>editors</multiple></term>
+    <term name="editor" form="short"><single>ed.</single><multiple>eds.</multiple></term>
+    <term name="editor" form="verb">edited by</term>
+    <term name="editor" form="verb-short">ed. by</term>
+    <term name="translator"><single>translator</single><multiple>translators</multiple></term>
+    <term name="translator" form="short"><single>tran.</single><multiple>trans.</multiple></term>
+    <term name="translator" form="verb">translated by</term>
+    <term name="translator" form="verb-short">trans. by</term>
+    <term name="container-author" form="verb">by</term>
+    <term name="month-01">January</term>
+    <term name="month-02">February</term>
+    <term name="month-03">March</term>
+    <term name="month-04">April</term>
+    <term name="month-05">May</term>
+    <term name="month-06">June</term>
+    <term name="month-07">July</term>
+    <term name="month-08">August</term>
+    <term name="month-09">September</term>
+    <term name="month-10">October</term>
+    <term name="month-11">November</term>
+    <term name="month-12">December</term>
+    <term name="month-01" form="short">Jan.</term>
+    <term name="month-02" form="short">Feb.</term>
+    <term name="month-03" form="short">Mar.</term>
+    <term name="month-04" form="short">Apr.</term>
+    <term name="month-05" form="short">May</term>
+    <term name="month-06" form="short">Jun.</term>
+    <term name="month-07" form="short">Jul.</term>
+    <term name="month-08" form="short">Aug.</term>
+    <term name="month-09" form="short">Sep.</term>
+    <term name="month-10" form="short">Oct.</term>
+    <term name="month-11" form="short">Nov.</term>
+    <term name="month-12" form="short">Dec.</term>
+  </terms>
+</locale>`)
+
+func mustParseCSL(s string) *cslNode {
+	var n cslNode
+	if err := xml.Unmarshal([]byte(s), &n); err != nil {
+		panic(err)
+	}
+	return &n
+}
+
+// cslTypes maps BibTeX entry types to CSL item types.
+var cslTypes = map[string]string{
+	"article":       "article-journal",
+	"inproceedings": "paper-conference",
+	"techreport":    "report",
+	"incollection":  "chapter",
+	"book":          "book",
+	"collection":    "book",
+	"proceedings":   "book",
+	"booklet":       "pamphlet",
+	"manual":        "report",
+	"inbook":        "chapter",
+	"phdthesis":     "thesis",
+	"mastersthesis": "thesis",
+	"thesis":        "thesis",
+	"misc":          "document",
+	"software":      "software",
+	"dataset":       "dataset",
+	"unpublished":   "manuscript",
+	"patent":        "patent",
+	"online":        "webpage",
+}
+
+// cslGenres are the default genres of thesis types.
+var cslGenres = map[string]string{
+	"phdthesis":     "PhD thesis",
+	"mastersthesis": "Master's thesis",
+}
+
+// A cslItem is a bibliography entry as CSL variables. Standard variables
+// hold HTML.
+type cslItem struct {
+	entry *bibEntry
+	typ   string
+	vars  map[string]string
+	names map[string][]name
+	dates map[string]cslDate
+}
+
+// A cslDate is a date variable. Dates that are not numeric, such as
+// "in press", are kept as literals.
+type cslDate struct {
+	year, month, day int
+	literal          string
+}
+
+func newCSLItem(e *bibEntry) (*cslItem, error) {
+	typ, ok := cslTypes[e.Type]
+	if !ok {
+		return nil, fmt.Errorf("invalid citation type %s", e.Type)
+	}
+	it := &cslItem{
+		entry: e,
+		typ:   typ,
+		vars:  make(map[string]string),
+		names: make(map[string][]name),
+		dates: make(map[string]cslDate),
+	}
+	set := func(v, field string) {
+		if it.vars[v] == "" {
+			it.vars[v] = strings.TrimSpace(e.field(field))
+		}
+	}
+	set("title", "title")
+	set("title-short", "shorttitle")
+	switch {
+	case e.Type == "article":
+		set("container-title", "journal")
+		set("issue", "number")
+	case e.Type == "inbook" && !e.has("booktitle"):
+		// In BibTeX, an inbook is a chapter or page range of the titled
+		// book, which is cited as the book with its chapter-number and page.
+		it.typ = "book"
+		set("number", "number")
+	default:
+		set("container-title", "booktitle")
+		set("number", "number")
+	}
+	set("chapter-number", "chapter")
+	set("version", "version")
+	set("medium", "howpublished")
+	set("collection-title", "series")
+	set("volume", "volume")
+	set("page", "pages")
+	set("edition", "edition")
+	set("publisher", "publisher")
+	set("publisher", "institution")
+	set("publisher", "organization")
+	set("publisher", "school")
+	set("publisher-place", "address")
+	set("genre", "type")
+	if it.vars["genre"] == "" {
+		it.vars["genre"] = cslGenres[e.Type]
+	}
+	set("note", "note")
+	set("keyword", "keywords")
+	set("ISBN", "isbn")
+	set("ISSN", "issn")
+	set("URL", "url")
+	if doi := e.doi(); doi != "" {
+		it.vars["DOI"] = doi
+	}
+	if id := e.arXiv(); id != "" {
+		// An identifier taken from the pages is shown there already.
+		if it.vars["number"] == "" && e.isArXiv() {
+			it.vars["number"] = "arXiv:" + id
+		}
+		if it.vars["URL"] == "" {
+			it.vars["URL"] = "https://arxiv.org/abs/" + id
+		}
+	}
+	for _, v := range []string{"author", "editor", "translator"} {
+		if names := e.names(v); len(names) > 0 {
+			it.names[v] = names
+		}
+	}
+
+	var issued cslDate
+	if y := e.field("year"); y != "" {
+		if n, err := strconv.Atoi(y); err == nil {
+			issued.year = n
+			issued.month = monthNumber(e.field("month"))
+			issued.day, _ = strconv.Atoi(e.field("day"))
+		} else {
+			issued.literal = y
+		}
+		it.dates["issued"] = issued
+	} else if d, ok := parseISODate(e.field("date")); ok {
+		it.dates["issued"] = d
+	}
+	if d, ok := parseISODate(e.field("urldate")); ok {
+		it.dates["accessed"] = d
+	}
+	return it, nil
+}
+
+// parseISODate parses a date of the form YYYY, YYYY-MM or YYYY-MM-DD.
+func parseISODate(s string) (cslDate, bool) {
+	var d cslDate
+	parts := strings.Split(s, "-")
+	if s == "" || len(parts) > 3 {
+		return d, false
+	}
+	var nums [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return d, false
+		}
+		nums[i] = n
+	}
+	return cslDate{year: nums[0], month: nums[1], day: nums[2]}, true
+}
+
+var quotePunctuation = strings.NewReplacer("”.", ".”", "”,", ",”", "’.", ".’", "’,", ",’")
+
+func (s *cslStyle) Format(e *bibEntry) (string, error) {
+	it, err := newCSLItem(e)
+	if err != nil {
+		return "", err
+	}
+	r := &cslRenderer{style: s, item: it, suppressed: make(map[string]bool)}
+	out := r.decorate(s.layout, r.seq(s.layout.Children, "").s)
+	if s.punctuationInQuote {
+		out = quotePunctuation.Replace(out)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// cslResult is the output of a rendering element, along with the number
+// of variables it called and how many of those were non-empty, which
+// decide whether an enclosing group is shown.
+type cslResult struct {
+	s              string
+	vars, nonEmpty int
+}
+
+func (r *cslResult) add(o cslResult) {
+	r.vars += o.vars
+	r.nonEmpty += o.nonEmpty
+}
+
+type cslRenderer struct {
+	style *cslStyle
+	item  *cslItem
+
+	// suppressed are variables that have been substituted for a names
+	// element and are not rendered again.
+	suppressed map[string]bool
+	// used collects the non-empty variables that are rendered, if set.
+	used *[]string
+}
+
+// seq renders nodes in order, joining non-empty output with delim.
+func (r *cslRenderer) seq(nodes []*cslNode, delim string) cslResult {
+	var res cslResult
+	for _, n := range nodes {
+		var o cslResult
+		if n.Name == "choose" {
+			// The chosen branch takes the place of cs:choose, so its
+			// output is delimited as part of the enclosing element.
+			o = r.seq(r.branch(n), delim)
+		} else {
+			o = r.node(n)
+		}
+		res.add(o)
+		if o.s == "" {
+			continue
+		}
+		if res.s != "" {
+			res.s = appendPunct(res.s, escapeHTML.Replace(delim))
+		}
+		res.s = appendPunct(res.s, o.s)
+	}
+	return res
+}
+
+func (r *cslRenderer) node(n *cslNode) cslResult {
+	switch n.Name {
+	case "text":
+		var res cslResult
+		switch {
+		case n.has("variable"):
+			res = r.variable(n.attr("variable"), n.attr("form"))
+			n, res.s = r.link(n, n.attr("variable"), res.s)
+		case n.has("macro"):
+			res = r.seq(r.style.macros[n.attr("macro")].Children, "")
+		case n.has("term"):
+			res.s = r.style.term(n.attr("term"), n.attr("form"), n.attr("plural") == "true")
+		case n.has("value"):
+			res.s = escapeHTML.Replace(n.attr("value"))
+		}
+		res.s = r.decorate(n, res.s)
+		return res
+	case "number":
+		res := r.variable(n.attr("variable"), "")
+		if num, err := strconv.Atoi(stripTags(res.s)); err == nil {
+			switch n.attr("form") {
+			case "ordinal", "long-ordinal":
+				res.s = r.style.ordinal(num)
+			case "roman":
+				res.s = roman(num)
+			}
+		}
+		res.s = r.decorate(n, res.s)
+		return res
+	case "label":
+		v := n.attr("variable")
+		val := stripTags(r.item.vars[v])
+		if val == "" || r.suppressed[v] {
+			return cslResult{}
+		}
+		plural := strings.ContainsAny(val, "-–,&")
+		if num, err := strconv.Atoi(val); err == nil {
+			plural = num > 1 && strings.HasPrefix(v, "number-of-")
+		}
+		switch n.attr("plural") {
+		case "always":
+			plural = true
+		case "never":
+			plural = false
+		}
+		return cslResult{s: r.decorate(n, r.style.term(v, n.attr("form"), plural))}
+	case "names":
+		return r.names(n, nil)
+	case "date":
+		return r.date(n)
+	case "group":
+		res := r.seq(n.Children, n.attr("delimiter"))
+		if res.vars > 0 && res.nonEmpty == 0 {
+			return cslResult{vars: res.vars}
+		}
+		res.s = r.decorate(n, res.s)
+		return res
+	case "choose":
+		return r.seq(r.branch(n), "")
+	}
+	return cslResult{}
+}
+
+// branch returns the children of the first branch of a cs:choose element
+// whose conditions hold.
+func (r *cslRenderer) branch(n *cslNode) []*cslNode {
+	for _, c := range n.Children {
+		if c.Name == "else" || r.test(c) {
+			return c.Children
+		}
+	}
+	return nil
+}
+
+// link makes a URL or DOI into an HTML link, with the text given by the
+// link mode of the entry. If the DOI is prefixed with a resolver such as
+// https://doi.org/ in the style, the prefix becomes part of the link and
+// is removed from the returned copy of n, as is a label such as "doi:"
+// if the link mode shows something other than the DOI.
+func (r *cslRenderer) link(n *cslNode, v, s string) (*cslNode, string) {
+	if s == "" {
+		return n, s
+	}
+	e := r.item.entry
+	switch v {
+	case "URL":
+		s = fmt.Sprintf("<a href=%s>%s</a>", s, e.linkText(s, ""))
+	case "DOI":
+		prefix := n.attr("prefix")
+		i := strings.Index(prefix, "http")
+		resolver := i >= 0 && strings.HasSuffix(prefix, "/")
+		if !resolver && (e.links == "" || e.links == "pages") {
+			return n, fmt.Sprintf("<a href=https://doi.org/%s>%s</a>", s, s)
+		}
+		u := "https://doi.org/" + s
+		if resolver {
+			u = prefix[i:] + s
+		} else if i = strings.LastIndex(strings.ToLower(prefix), "doi"); i < 0 {
+			i = len(prefix)
+		}
+		s = fmt.Sprintf("<a href=%s>%s</a>", u, e.linkText(u, ""))
+		c := *n
+		c.Attrs = make(map[string]string)
+		for k, v := range n.Attrs {
+			c.Attrs[k] = v
+		}
+		c.Attrs["prefix"] = prefix[:i]
+		n = &c
+	}
+	return n, s
+}
+
+func (r *cslRenderer) variable(v, form string) cslResult {
+	res := cslResult{vars: 1}
+	if r.suppressed[v] {
+		return res
+	}
+	s := ""
+	if form == "short" {
+		s = r.item.vars[v+"-short"]
+	}
+	if s == "" {
+		s = r.item.vars[v]
+	}
+	if s == "" {
+		return res
+	}
+	if r.used != nil {
+		*r.used = append(*r.used, v)
+	}
+	res.s = s
+	res.nonEmpty = 1
+	return res
+}
+
+// hasVariable reports whether the item has a value for v of any kind.
+func (r *cslRenderer) hasVariable(v string) bool {
+	if r.suppressed[v] {
+		return false
+	}
+	_, isDate := r.item.dates[v]
+	return r.item.vars[v] != "" || len(r.item.names[v]) > 0 || isDate
+}
+
+var matchNumeric = regexp.MustCompile(`^[a-zA-Z]*\d+[a-zA-Z]*(\s*[-–,&]\s*[a-zA-Z]*\d+[a-zA-Z]*)*$`)
+
+// test evaluates the conditions of a cs:if or cs:else-if element.
+func (r *cslRenderer) test(n *cslNode) bool {
+	var results []bool
+	for attr, val := range n.Attrs {
+		for _, v := range strings.Fields(val) {
+			switch attr {
+			case "type":
+				results = append(results, r.item.typ == v)
+			case "variable":
+				results = append(results, r.hasVariable(v))
+			case "is-numeric":
+				results = append(results, matchNumeric.MatchString(stripTags(r.item.vars[v])))
+			case "is-uncertain-date", "locator", "position", "disambiguate":
+				// These only apply to citations and ambiguous entries.
+				results = append(results, false)
+			}
+		}
+	}
+	switch n.attr("match") {
+	case "any":
+		for _, ok := range results {
+			if ok {
+				return true
+			}
+		}
+		return false
+	case "none":
+		for _, ok := range results {
+			if ok {
+				return false
+			}
+		}
+		return true
+	default:
+		for _, ok := range results {
+			if !ok {
+				return false
+			}
+		}
+		return len(results) > 0
+	}
+}
+
+// names renders a cs:names element. Within cs:substitute, a names element
+// without children takes its name, et-al and label from parent.
+func (r *cslRenderer) names(n, parent *cslNode) cslResult {
+	src := n
+	if len(n.Children) == 0 && parent != nil {
+		src = parent
+	}
+	vars := strings.Fields(n.attr("variable"))
+	res := cslResult{vars: len(vars)}
+	var parts []string
+	for _, v := range vars {
+		list := r.item.names[v]
+		if r.suppressed[v] || len(list) == 0 {
+			continue
+		}
+		s := r.nameList(list, src.child("name"), src.child("et-al"))
+		// The label goes before or after the names, following the order
+		// of the elements.
+		labelFirst := false
+		for _, c := range src.Children {
+			if c.Name == "name" || c.Name == "label" {
+				labelFirst = c.Name == "label"
+				break
+			}
+		}
+		if l := src.child("label"); l != nil {
+			label := r.decorate(l, r.style.term(v, l.attr("form"), len(list) > 1))
+			if labelFirst {
+				s = label + s
+			} else {
+				s += label
+			}
+		}
+		if r.used != nil {
+			*r.used = append(*r.used, v)
+		}
+		parts = append(parts, s)
+		res.nonEmpty++
+	}
+	if len(parts) == 0 {
+		if sub := n.child("substitute"); sub != nil {
+			for _, c := range sub.Children {
+				var used []string
+				prev := r.used
+				r.used = &used
+				var o cslResult
+				if c.Name == "names" {
+					o = r.names(c, n)
+				} else {
+					o = r.node(c)
+				}
+				r.used = prev
+				if o.s == "" {
+					continue
+				}
+				for _, v := range used {
+					r.suppressed[v] = true
+				}
+				if prev != nil {
+					*prev = append(*prev, used...)
+				}
+				o.s = r.decorate(n, o.s)
+				return o
+			}
+		}
+		return res
+	}
+	delim := r.style.options["names-delimiter"]
+	if n.has("delimiter") {
+		delim = n.attr("delimiter")
+	}
+	res.s = r.decorate(n, strings.Join(parts, escapeHTML.Replace(delim)))
+	return res
+}
+
+// nameList formats the names of one variable according to a cs:name
+// element, which may be nil.
+func (r *cslRenderer) nameList(list []name, nameNode, etAlNode *cslNode) string {
+	opts := make(map[string]string)
+	for k, v := range r.style.options {
+		opts[k] = v
+	}
+	if nameNode != nil {
+		for k, v := range nameNode.Attrs {
+			opts[k] = v
+		}
+	}
+	delim := ", "
+	if d, ok := opts["delimiter"]; ok {
+		delim = d
+	}
+	delim = escapeHTML.Replace(delim)
+
+	etAl := false
+	var people []name
+	for _, p := range list {
+		if p.isOthers() {
+			etAl = true
+		} else {
+			people = append(people, p)
+		}
+	}
+	shown := people
+	min, _ := strconv.Atoi(opts["et-al-min"])
+	first, _ := strconv.Atoi(opts["et-al-use-first"])
+	if min > 0 && first > 0 && len(people) >= min && first < len(people) {
+		shown = people[:first]
+		etAl = true
+	}
+	if opts["form"] == "count" {
+		return strconv.Itoa(len(shown))
+	}
+
+	inverted := func(i int) bool {
+		return opts["name-as-sort-order"] == "all" || opts["name-as-sort-order"] == "first" && i == 0
+	}
+	var formatted []string
+	for i, p := range shown {
+		formatted = append(formatted, r.formatName(p, inverted(i), opts, nameNode))
+	}
+	if len(formatted) == 0 {
+		return ""
+	}
+	s := formatted[0]
+	if etAl {
+		// Truncated lists have no "and" before the last name shown.
+		s = strings.Join(formatted, delim)
+	} else if len(formatted) > 1 {
+		last := len(formatted) - 1
+		s = strings.Join(formatted[:last], delim)
+		and := ""
+		switch opts["and"] {
+		case "text":
+			and = r.style.term("and", "long", false)
+		case "symbol":
+			and = "&amp;"
+		}
+		if and == "" {
+			s += delim
+		} else if precedes(opts["delimiter-precedes-last"], len(formatted) > 2, inverted(last-1)) {
+			s += delim + and + " "
+		} else {
+			s += " " + and + " "
+		}
+		s += formatted[last]
+	}
+	if etAl {
+		if opts["et-al-use-last"] == "true" && len(people) > len(shown)+1 {
+			p := people[len(people)-1]
+			return s + delim + "… " + r.formatName(p, inverted(len(people)-1), opts, nameNode)
+		}
+		term := "et-al"
+		if etAlNode.has("term") {
+			term = etAlNode.attr("term")
+		}
+		t := r.style.term(term, "long", false)
+		if etAlNode != nil {
+			t = r.decorate(etAlNode, t)
+		}
+		if precedes(opts["delimiter-precedes-et-al"], len(shown) > 1, inverted(len(shown)-1)) {
+			s += delim + t
+		} else {
+			s += " " + t
+		}
+	}
+	return s
+}
+
+// precedes evaluates a delimiter-precedes-last or delimiter-precedes-et-al
+// option.
+func precedes(option string, contextual, afterInverted bool) bool {
+	switch option {
+	case "always":
+		return true
+	case "never":
+		return false
+	case "after-inverted-name":
+		return afterInverted
+	default:
+		return contextual
+	}
+}
+
+func (r *cslRenderer) formatName(p name, inverted bool, opts map[string]string, nameNode *cslNode) string {
+	var familyPart, givenPart *cslNode
+	if nameNode != nil {
+		for _, c := range nameNode.Children {
+			switch {
+			case c.Name == "name-part" && c.attr("name") == "family":
+				familyPart = c
+			case c.Name == "name-part" && c.attr("name") == "given":
+				givenPart = c
+			}
+		}
+	}
+	decorate := func(n *cslNode, s string) string {
+		if n == nil {
+			return s
+		}
+		return r.decorate(n, s)
+	}
+	if p.Corporate {
+		return markName(decorate(familyPart, p.Last), p)
+	}
+	family := decorate(familyPart, p.family())
+	if opts["form"] == "short" {
+		return markName(family, p)
+	}
+	given := p.First
+	if iw, ok := opts["initialize-with"]; ok && opts["initialize"] != "false" {
+		given = initialsWith(p.Initials, escapeHTML.Replace(iw))
+	}
+	given = decorate(givenPart, given)
+
+	var s string
+	if inverted {
+		sep := ", "
+		if v, ok := opts["sort-separator"]; ok {
+			sep = escapeHTML.Replace(v)
+		}
+		s = family
+		if given != "" {
+			s += sep + given
+		}
+		if p.Jr != "" {
+			s += sep + p.Jr
+		}
+	} else {
+		s = strings.TrimSpace(given + " " + family)
+		if p.Jr != "" {
+			s += " " + p.Jr
+		}
+	}
+	return markName(s, p)
+}
+
+// initialsWith rewrites initials such as "C.W." or "J.-P." using a
+// different separator, e.g. "C. W." for ". ".
+func initialsWith(initials, with string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(initials, ".") {
+		if part == "" {
+			continue
+		}
+		if strings.HasPrefix(part, "-") {
+			s := strings.TrimRight(b.String(), " ")
+			b.Reset()
+			b.WriteString(s)
+		}
+		b.WriteString(part + with)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// date renders a cs:date element, using the locale's format if the
+// element has a form.
+func (r *cslRenderer) date(n *cslNode) cslResult {
+	v := n.attr("variable")
+	res := cslResult{vars: 1}
+	d, ok := r.item.dates[v]
+	if !ok || r.suppressed[v] {
+		return res
+	}
+	res.nonEmpty = 1
+	if d.literal != "" {
+		res.s = r.decorate(n, d.literal)
+		return res
+	}
+	parts := n.Children
+	delim := n.attr("delimiter")
+	if loc := r.style.dates[n.attr("form")]; n.has("form") && loc != nil {
+		include := map[string]bool{"year": true, "month": true, "day": true}
+		switch n.attr("date-parts") {
+		case "year-month":
+			include["day"] = false
+		case "year":
+			include["day"], include["month"] = false, false
+		}
+		parts = nil
+		for _, p := range loc.Children {
+			if !include[p.attr("name")] {
+				continue
+			}
+			// Date parts given in the style override the locale's
+			// formatting, but not its affixes.
+			merged := &cslNode{Name: p.Name, Attrs: make(map[string]string)}
+			for k, v := range p.Attrs {
+				merged.Attrs[k] = v
+			}
+			for _, o := range n.Children {
+				if o.attr("name") != p.attr("name") {
+					continue
+				}
+				for k, v := range o.Attrs {
+					if k != "prefix" && k != "suffix" {
+						merged.Attrs[k] = v
+					}
+				}
+			}
+			parts = append(parts, merged)
+		}
+		delim = loc.attr("delimiter")
+	}
+	var out []string
+	for _, p := range parts {
+		if p.Name != "date-part" {
+			continue
+		}
+		if s := r.datePart(d, p); s != "" {
+			out = append(out, r.decorate(p, s))
+		}
+	}
+	res.s = r.decorate(n, strings.TrimSpace(strings.Join(out, escapeHTML.Replace(delim))))
+	return res
+}
+
+func (r *cslRenderer) datePart(d cslDate, p *cslNode) string {
+	form := p.attr("form")
+	switch p.attr("name") {
+	case "year":
+		if d.year == 0 {
+			return ""
+		}
+		if form == "short" {
+			return fmt.Sprintf("%02d", d.year%100)
+		}
+		return strconv.Itoa(d.year)
+	case "month":
+		if d.month == 0 {
+			return ""
+		}
+		switch form {
+		case "numeric":
+			return strconv.Itoa(d.month)
+		case "numeric-leading-zeros":
+			return fmt.Sprintf("%02d", d.month)
+		}
+		return r.style.term(fmt.Sprintf("month-%02d", d.month), form, false)
+	case "day":
+		if d.day == 0 {
+			return ""
+		}
+		switch form {
+		case "numeric-leading-zeros":
+			return fmt.Sprintf("%02d", d.day)
+		case "ordinal":
+			return r.style.ordinal(d.day)
+		}
+		return strconv.Itoa(d.day)
+	}
+	return ""
+}
+
+// decorate applies the text case, quotes, formatting and affixes of n to
+// the HTML s.
+func (r *cslRenderer) decorate(n *cslNode, s string) string {
+	if s == "" {
+		return ""
+	}
+	if c := n.attr("text-case"); c != "" {
+		s = textCase(s, c)
+	}
+	if n.attr("strip-periods") == "true" {
+		s = mapWords(s, func(w string, _, _ bool) string { return strings.Replace(w, ".", "", -1) })
+	}
+	if n.attr("quotes") == "true" {
+		s = r.style.term("open-quote", "long", false) + s + r.style.term("close-quote", "long", false)
+	}
+	switch n.attr("font-style") {
+	case "italic", "oblique":
+		s = "<i>" + s + "</i>"
+	}
+	if n.attr("font-variant") == "small-caps" {
+		s = "<span style='font-variant:small-caps'>" + s + "</span>"
+	}
+	if n.attr("font-weight") == "bold" {
+		s = "<strong>" + s + "</strong>"
+	}
+	if n.attr("text-decoration") == "underline" {
+		s = "<u>" + s + "</u>"
+	}
+	switch n.attr("vertical-align") {
+	case "sup":
+		s = "<sup>" + s + "</sup>"
+	case "sub":
+		s = "<sub>" + s + "</sub>"
+	}
+	return appendPunct(escapeHTML.Replace(n.attr("prefix"))+s, escapeHTML.Replace(n.attr("suffix")))
+}
+
+// appendPunct appends b to a, dropping a leading period from b if a
+// already ends with terminal punctuation.
+func appendPunct(a, b string) string {
+	if strings.HasPrefix(b, ".") && strings.ContainsAny(lastVisible(strings.TrimRight(stripTags(a), "”’")), ".?!") {
+		b = b[1:]
+	}
+	return a + b
+}
+
+// textCase applies a CSL text-case to the HTML s.
+func textCase(s, c string) string {
+	upper := stripTags(s) == strings.ToUpper(stripTags(s))
+	switch c {
+	case "lowercase":
+		return mapWords(s, func(w string, _, _ bool) string { return strings.ToLower(w) })
+	case "uppercase":
+		return mapWords(s, func(w string, _, _ bool) string { return strings.ToUpper(w) })
+	case "capitalize-first":
+		return mapWords(s, func(w string, first, _ bool) string {
+			if first {
+				return upperFirst(w)
+			}
+			return w
+		})
+	case "capitalize-all":
+		return mapWords(s, func(w string, _, _ bool) string { return upperFirst(w) })
+	case "sentence":
+		return mapWords(s, func(w string, first, _ bool) string {
+			if upper {
+				w = strings.ToLower(w)
+			}
+			if first {
+				return upperFirst(w)
+			}
+			return w
+		})
+	case "title":
+		return mapWords(s, func(w string, first, last bool) string {
+			if upper {
+				w = strings.ToLower(w)
+			}
+			if w != strings.ToLower(w) || !first && !last && titleStopWords[w] {
+				return w
+			}
+			return upperFirst(w)
+		})
+	}
+	return s
+}
+
+var titleStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "as": true, "at": true, "but": true, "by": true,
+	"down": true, "for": true, "from": true, "in": true, "into": true, "nor": true,
+	"of": true, "on": true, "onto": true, "or": true, "over": true, "so": true,
+	"the": true, "till": true, "to": true, "up": true, "via": true, "with": true, "yet": true,
+}
+
+func upperFirst(w string) string {
+	r, n := utf8.DecodeRuneInString(w)
+	if r == utf8.RuneError {
+		return w
+	}
+	return string(unicode.ToUpper(r)) + w[n:]
+}
+
+// mapWords applies f to each word of the text in the HTML fragment s,
+// leaving tags and character references unchanged.
+func mapWords(s string, f func(w string, first, last bool) string) string {
+	type token struct {
+		s    string
+		word bool
+	}
+	var tokens []token
+	for len(s) > 0 {
+		i := 0
+		switch {
+		case s[0] == '<':
+			i = strings.IndexByte(s, '>') + 1
+		case s[0] == '&' && strings.IndexByte(s, ';') > 0:
+			i = strings.IndexByte(s, ';') + 1
+		case s[0] == ' ':
+			i = len(s) - len(strings.TrimLeft(s, " "))
+		default:
+			i = strings.IndexAny(s, " <&")
+			if i == 0 {
+				i = 1
+			}
+			if i < 0 {
+				i = len(s)
+			}
+			tokens = append(tokens, token{s[:i], true})
+			s = s[i:]
+			continue
+		}
+		if i <= 0 {
+			i = len(s)
+		}
+		tokens = append(tokens, token{s: s[:i]})
+		s = s[i:]
+	}
+	var words []int
+	for i, t := range tokens {
+		if t.word {
+			words = append(words, i)
+		}
+	}
+	var b strings.Builder
+	for i, t := range tokens {
+		if t.word {
+			t.s = f(t.s, i == words[0], i == words[len(words)-1])
+		}
+		b.WriteString(t.s)
+	}
+	return b.String()
+}
+
+func roman(n int) string {
+	if n <= 0 || n >= 4000 {
+		return strconv.Itoa(n)
+	}
+	numerals := []struct {
+		v int
+		s string
+	}{{1000, "m"}, {900, "cm"}, {500, "d"}, {400, "cd"}, {100, "c"}, {90, "xc"},
+		{50, "l"}, {40, "xl"}, {10, "x"}, {9, "ix"}, {5, "v"}, {4, "iv"}, {1, "i"}}
+	var b strings.Builder
+	for _, r := range numerals {
+		for n >= r.v {
+			b.WriteString(r.s)
+			n -= r.v
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cslTestEntries exercise the parts of testdata/test.csl: name lists
+// with and without et al., substitution of the editors or title for
+// missing authors, dates, choose conditions on type and variables,
+// affixes and quotes, and DOI and URL links.
+const cslTestEntries = `
+@article{two, author = {Tessum, Christopher W. and Hill, Jason D.}, title = {Air quality}, journal = {Environ. Sci. Technol.}, volume = {48}, number = {2}, pages = {10--20}, year = {2014}, month = dec, doi = {10.1021/es1}}
+@article{three, author = {Doe, Jane and Roe, Richard and Poe, Edgar}, title = {Is it three?}, journal = {Nature}, year = {2020}, url = {https://example.com/3}}
+@article{five, author = {Doe, Jane and Roe, Richard and Poe, Edgar and Hill, Jason D. and Marshall, Julian D.}, title = {Many authors}, journal = {Science}, year = {2021}}
+@book{edited, editor = {Roe, Richard and Poe, Edgar}, title = {Edited volume}, publisher = {Wiley}, year = {2017}}
+@misc{bare, title = {Only a title}}
+@incollection{chapter, author = {Hill, Jason D.}, title = {A chapter}, booktitle = {A book}, editor = {Roe, Richard and Poe, Edgar}, year = {2018}, url = {https://example.com/c}}
+@techreport{report, author = {Hill, Jason D.}, title = {A report}, institution = {EPA}, year = {2010}, doi = {10.1000/r}}
+`
+
+func TestCSLStyle(t *testing.T) {
+	citations := testBib(t, cslTestEntries)
+	s, err := loadCSL(filepath.Join("testdata", "test.csl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ key, want string }{
+		{"two", "Tessum, C. W. and J. D. Hill. (Dec. 2014). “Air quality.” <i>Environ. Sci. Technol.</i> <strong>48</strong> (2): 10–20. <a href=https://doi.org/10.1021/es1>https://doi.org/10.1021/es1</a>."},
+		{"three", "Doe, J., R. Roe, and E. Poe. (2020). “Is it three?” <i>Nature</i>. <a href=https://example.com/3>https://example.com/3</a>."},
+		{"five", "Doe, J., R. Roe, <i>et al.</i> (2021). “Many authors.” <i>Science</i>."},
+		{"edited", "Roe, R. and E. Poe (eds.). (2017). <i>Edited volume</i>. Wiley."},
+		{"bare", "<i>Only a title</i>. (n.d.)."},
+		{"chapter", "Hill, J. D. (2018). “A chapter.” In <i>A book</i> (R. Roe &amp; E. Poe, eds.). <a href=https://example.com/c>https://example.com/c</a>."},
+		{"report", "Hill, J. D. (2010). <i>A report</i>. EPA. doi:<a href=https://doi.org/10.1000/r>10.1000/r</a>."},
+	} {
+		got, err := s.Format(citations[template.HTML(tt.key)])
+		if err != nil {
+			t.Errorf("%s: %v", tt.key, err)
+		} else if got != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestCSLLinks(t *testing.T) {
+	citations := testBib(t, cslTestEntries)
+	s, err := loadCSL(filepath.Join("testdata", "test.csl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		key  template.HTML
+		want map[string]string // by link mode
+	}{
+		{"two", map[string]string{
+			"":      "<a href=https://doi.org/10.1021/es1>https://doi.org/10.1021/es1</a>",
+			"pages": "<a href=https://doi.org/10.1021/es1>https://doi.org/10.1021/es1</a>",
+			"url":   "<a href=https://doi.org/10.1021/es1>https://doi.org/10.1021/es1</a>",
+			"doi":   "<a href=https://doi.org/10.1021/es1>doi:10.1021/es1</a>",
+			"link":  "<a href=https://doi.org/10.1021/es1>link</a>",
+		}},
+		{"three", map[string]string{
+			"":      "<a href=https://example.com/3>https://example.com/3</a>",
+			"pages": "<a href=https://example.com/3>https://example.com/3</a>",
+			"url":   "<a href=https://example.com/3>https://example.com/3</a>",
+			"doi":   "<a href=https://example.com/3>https://example.com/3</a>",
+			"link":  "<a href=https://example.com/3>link</a>",
+		}},
+		{"report", map[string]string{
+			"":      "doi:<a href=https://doi.org/10.1000/r>10.1000/r</a>",
+			"pages": "doi:<a href=https://doi.org/10.1000/r>10.1000/r</a>",
+			"url":   "<a href=https://doi.org/10.1000/r>https://doi.org/10.1000/r</a>",
+			"doi":   "<a href=https://doi.org/10.1000/r>doi:10.1000/r</a>",
+			"link":  "<a href=https://doi.org/10.1000/r>link</a>",
+		}},
+	} {
+		for mode, want := range tt.want {
+			got, err := linkStyle{s, mode}.Format(citations[tt.key])
+			if err != nil {
+				t.Errorf("%s %q: %v", tt.key, mode, err)
+			} else if !strings.HasSuffix(got, " "+want+".") {
+				t.Errorf("%s %q: %q does not end with %q", tt.key, mode, got, want)
+			}
+		}
+	}
+}
+
+func TestLoadCSLErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct{ style, err string }{
+		{`<locale xml:lang="en"/>`, "not a CSL style"},
+		{`<style><citation><layout/></citation></style>`, "style has no bibliography layout"},
+		{`<style><info><link rel="independent-parent" href="apa.csl"/></info></style>`, "dependent style; use its parent apa.csl instead"},
+		{`<style><bibliography><layout><text macro="nope"/></layout></bibliography></style>`, "undefined macro nope"},
+		{`<style><macro name="m"><text macro="m"/></macro><bibliography><layout><text macro="m"/></layout></bibliography></style>`, "macros nested too deeply"},
+		{`<style><bibliography>`, "unexpected EOF"},
+	} {
+		f := filepath.Join(dir, "style.csl")
+		if err := os.WriteFile(f, []byte(tt.style), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadCSL(f); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("loadCSL(%s) = %v, want %q", tt.style, err, tt.err)
+		}
+	}
+}
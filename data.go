@@ -0,0 +1,833 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Data is the contents of a CV data file: a master list of sections and
+// the documents that are derived from it.
+type Data struct {
+	Owner          Owner      `yaml:"owner"`
+	Bibliographies []string   `yaml:"bibliographies"`
+	Sections       []Section  `yaml:"sections"`
+	Documents      []Document `yaml:"documents"`
+	Roles          []Role     `yaml:"roles"`
+	People         []Person   `yaml:"people"`
+	Markers        Markers    `yaml:"markers"`
+
+	file string
+}
+
+// Owner is the person whose CV this is, shown at the top of each document.
+type Owner struct {
+	Name    string `yaml:"name"`
+	Email   string `yaml:"email"`
+	Website string `yaml:"website"`
+	ORCID   string `yaml:"orcid"`
+}
+
+// contactLines returns the owner's contact details as lines of HTML, as
+// shown in the heading of each document.
+func (o Owner) contactLines() []string {
+	var contact []string
+	if o.Email != "" {
+		contact = append(contact, html.EscapeString(o.Email))
+	}
+	if o.ORCID != "" {
+		id := html.EscapeString(o.ORCID)
+		contact = append(contact, fmt.Sprintf("<a href=https://orcid.org/%s>ORCID: %s</a>", id, id))
+	}
+	var lines []string
+	if len(contact) > 0 {
+		lines = append(lines, strings.Join(contact, " · "))
+	}
+	if o.Website != "" {
+		u := html.EscapeString(o.Website)
+		lines = append(lines, fmt.Sprintf("<a href=%s>%s</a>", u, u))
+	}
+	return lines
+}
+
+// Document is a single rendered output, such as the full CV or a resume.
+type Document struct {
+	ID       string       `yaml:"id"`
+	Output   string       `yaml:"output"`
+	Style    string       `yaml:"style"`
+	Sections []SectionRef `yaml:"sections"`
+
+	// Authors, if set, shortens the author lists of sections that do
+	// not set their own.
+	Authors *Truncation `yaml:"authors"`
+	// Links selects the text shown for citation links; see linkModes.
+	Links string `yaml:"links"`
+	// Header and Footer, if set, are shown on every page of the PDF.
+	Header *PageText `yaml:"header"`
+	Footer *PageText `yaml:"footer"`
+	// Print is the page setup of the PDF.
+	Print PrintSettings `yaml:"print"`
+	// Title, Subject and Keywords are the metadata of the PDF, along
+	// with the owner as its author. Title defaults to the owner's name
+	// followed by "CV".
+	Title    string     `yaml:"title"`
+	Subject  string     `yaml:"subject"`
+	Keywords stringList `yaml:"keywords"`
+	// LaTeX selects how the document is exported to LaTeX.
+	LaTeX LaTeXOptions `yaml:"latex"`
+
+	line int
+}
+
+// SectionRef refers to a master section by ID, optionally overriding
+// parts of it for a particular document. In the data file it can be
+// written either as a bare ID or as a mapping.
+type SectionRef struct {
+	Ref       string          `yaml:"ref"`
+	Name      template.HTML   `yaml:"name"`
+	Items     []string        `yaml:"items"`
+	Citations []template.HTML `yaml:"citations"`
+	Max       int             `yaml:"max"`
+	Authors   *Truncation     `yaml:"authors"`
+
+	line int
+}
+
+func (r *SectionRef) UnmarshalYAML(n *yaml.Node) error {
+	r.line = n.Line
+	if n.Kind == yaml.ScalarNode {
+		r.Ref = n.Value
+		return nil
+	}
+	type plain SectionRef
+	return decodeStrict(n, (*plain)(r), "section reference")
+}
+
+// style returns the citation style of the document.
+func (doc Document) style() (Style, error) {
+	s, err := lookupStyle(doc.Style)
+	if err != nil || doc.Links == "" {
+		return s, err
+	}
+	return linkStyle{s, doc.Links}, nil
+}
+
+func (d *Document) UnmarshalYAML(n *yaml.Node) error {
+	d.Print = defaultPrintSettings
+	type plain Document
+	if err := decodeStrict(n, (*plain)(d), "document"); err != nil {
+		return err
+	}
+	d.line = n.Line
+	return nil
+}
+
+func (s *Section) UnmarshalYAML(n *yaml.Node) error {
+	type plain Section
+	if err := decodeStrict(n, (*plain)(s), "section"); err != nil {
+		return err
+	}
+	s.line = n.Line
+	s.citationLines = make(map[template.HTML]int)
+	for _, key := range []string{"citations", "include", "exclude"} {
+		if c := mappingValue(n, key); c != nil {
+			for _, k := range c.Content {
+				s.citationLines[template.HTML(k.Value)] = k.Line
+			}
+		}
+	}
+	return nil
+}
+
+// A Query selects the bibliography entries that make up a citation
+// section. An entry matches if it meets every condition that is given.
+type Query struct {
+	Types    stringList `yaml:"type"`     // any of these entry types
+	Keywords stringList `yaml:"keywords"` // all of these keywords
+	Files    stringList `yaml:"file"`     // any of these bibliographies
+	MinYear  int        `yaml:"min-year"`
+	MaxYear  int        `yaml:"max-year"`
+
+	line int
+}
+
+func (q *Query) UnmarshalYAML(n *yaml.Node) error {
+	type plain Query
+	if err := decodeStrict(n, (*plain)(q), "query"); err != nil {
+		return err
+	}
+	q.line = n.Line
+	return nil
+}
+
+// Sort orders citations by publication date, using the year, month and
+// day fields. In the data file it can be written as just the order.
+type Sort struct {
+	Order    string `yaml:"order"`    // "desc" (newest first, the default) or "asc"
+	TieBreak string `yaml:"tiebreak"` // "key" (the default) or "author"
+
+	line int
+}
+
+func (o *Sort) UnmarshalYAML(n *yaml.Node) error {
+	o.line = n.Line
+	if n.Kind == yaml.ScalarNode {
+		o.Order = n.Value
+		return nil
+	}
+	type plain Sort
+	return decodeStrict(n, (*plain)(o), "sort")
+}
+
+func (o *Sort) validate() error {
+	switch o.Order {
+	case "", "asc", "desc":
+	default:
+		return fmt.Errorf("unknown sort order '%s' (want asc or desc)", o.Order)
+	}
+	switch o.TieBreak {
+	case "", "key", "author":
+	default:
+		return fmt.Errorf("unknown sort tiebreak '%s' (want key or author)", o.TieBreak)
+	}
+	return nil
+}
+
+// apply sorts keys. Keys that are not in citations go last.
+func (o *Sort) apply(keys []template.HTML, citations map[template.HTML]*bibEntry) {
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := citations[keys[i]], citations[keys[j]]
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		if c := compareDates(entryDate(a), entryDate(b)); c != 0 {
+			if o.Order == "asc" {
+				return c < 0
+			}
+			return c > 0
+		}
+		if o.TieBreak == "author" {
+			if c := strings.Compare(firstAuthor(a), firstAuthor(b)); c != 0 {
+				return c < 0
+			}
+		}
+		return strings.ToLower(string(keys[i])) < strings.ToLower(string(keys[j]))
+	})
+}
+
+// Truncation shortens long author lists to the first few authors followed
+// by "et al." In the data file it can be written as just the number of
+// authors shown.
+type Truncation struct {
+	Max         int  `yaml:"max"`         // longer lists are shortened; defaults to Show
+	Show        int  `yaml:"show"`        // number of leading authors kept
+	Highlighted bool `yaml:"highlighted"` // also keep later authors with a role or marker
+
+	line int
+}
+
+func (t *Truncation) UnmarshalYAML(n *yaml.Node) error {
+	t.line = n.Line
+	if n.Kind == yaml.ScalarNode {
+		return n.Decode(&t.Show)
+	}
+	type plain Truncation
+	return decodeStrict(n, (*plain)(t), "authors")
+}
+
+func (t *Truncation) validate() error {
+	if t.Show < 1 {
+		return fmt.Errorf("authors: show must be at least 1")
+	}
+	if t.Max != 0 && t.Max < t.Show {
+		return fmt.Errorf("authors: max (%d) is less than show (%d)", t.Max, t.Show)
+	}
+	return nil
+}
+
+// apply shortens names if needed, replacing the omitted authors with the
+// BibTeX "others" placeholder. A nil Truncation keeps all names.
+func (t *Truncation) apply(names []name) []name {
+	if t == nil {
+		return names
+	}
+	list, _ := trimOthers(names)
+	max := t.Max
+	if max == 0 {
+		max = t.Show
+	}
+	if len(list) <= max {
+		return names
+	}
+	out := slices.Clone(list[:t.Show])
+	if t.Highlighted {
+		for _, n := range list[t.Show:] {
+			if n.Role != nil || len(n.Markers) > 0 {
+				out = append(out, n)
+			}
+		}
+	}
+	return append(out, name{Last: "others"})
+}
+
+// entryDate returns the year, month and day of publication of e, with
+// zero for unknown parts. Years that are not numbers, such as "in press",
+// are later than any numeric year.
+func entryDate(e *bibEntry) [3]int {
+	if !e.has("year") {
+		d, _ := parseISODate(e.field("date"))
+		return [3]int{d.year, d.month, d.day}
+	}
+	y, err := strconv.Atoi(e.field("year"))
+	if err != nil {
+		y = math.MaxInt
+	}
+	day, _ := strconv.Atoi(e.field("day"))
+	return [3]int{y, monthNumber(e.field("month")), day}
+}
+
+func compareDates(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return cmp.Compare(a[i], b[i])
+		}
+	}
+	return 0
+}
+
+// firstAuthor returns the family name of the first author of e, for
+// sorting.
+func firstAuthor(e *bibEntry) string {
+	names := parseNames(e.rawField("author"))
+	if len(names) == 0 {
+		names = parseNames(e.rawField("editor"))
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return strings.ToLower(stripTags(names[0].Last))
+}
+
+// A stringList is a list of strings that can also be written as a single
+// string in the data file.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*l = stringList{n.Value}
+		return nil
+	}
+	return n.Decode((*[]string)(l))
+}
+
+// match reports whether e is selected by q.
+func (q *Query) match(e *bibEntry) bool {
+	if len(q.Types) > 0 && !containsFold(q.Types, e.Type) {
+		return false
+	}
+	if len(q.Files) > 0 && !slices.Contains(q.Files, e.File) {
+		return false
+	}
+	keywords := strings.FieldsFunc(e.field("keywords"), func(r rune) bool { return r == ',' || r == ';' })
+	for i, k := range keywords {
+		keywords[i] = strings.TrimSpace(k)
+	}
+	for _, k := range q.Keywords {
+		if !containsFold(keywords, k) {
+			return false
+		}
+	}
+	if q.MinYear != 0 || q.MaxYear != 0 {
+		y, err := strconv.Atoi(e.year())
+		if err != nil || q.MinYear != 0 && y < q.MinYear || q.MaxYear != 0 && y > q.MaxYear {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (item *Item) UnmarshalYAML(n *yaml.Node) error {
+	type plain Item
+	if err := decodeStrict(n, (*plain)(item), "item"); err != nil {
+		return err
+	}
+	item.line = n.Line
+	return nil
+}
+
+// decodeStrict decodes n into v, a pointer to a struct, rejecting mapping
+// keys that do not correspond to a field of v. It is needed because
+// custom unmarshalers do not inherit the strictness of the decoder.
+func decodeStrict(n *yaml.Node, v interface{}, what string) error {
+	if n.Kind == yaml.MappingNode {
+		t := reflect.TypeOf(v).Elem()
+		for i := 0; i < len(n.Content); i += 2 {
+			k := n.Content[i]
+			if !hasField(t, k.Value) {
+				return fmt.Errorf("line %d: field %s not found in %s", k.Line, k.Value, what)
+			}
+		}
+	}
+	return n.Decode(v)
+}
+
+func hasField(t reflect.Type, name string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		tag, opts, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag == name || opts == "inline" && hasField(t.Field(i).Type, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// mappingValue returns the value for key in mapping node n, or nil.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// loadData reads and validates a CV data file. If the file can be parsed
+// but is invalid, both the data and the validation error are returned.
+func loadData(filename string) (*Data, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	d := &Data{file: filename, Markers: defaultMarkers}
+	if err := dec.Decode(d); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	// Bibliographies and CSL styles are relative to the data file.
+	for i, bib := range d.Bibliographies {
+		if !filepath.IsAbs(bib) {
+			d.Bibliographies[i] = filepath.Join(filepath.Dir(filename), bib)
+		}
+	}
+	for _, s := range d.Sections {
+		if s.Query == nil {
+			continue
+		}
+		for i, f := range s.Query.Files {
+			if !filepath.IsAbs(f) {
+				s.Query.Files[i] = filepath.Join(filepath.Dir(filename), f)
+			}
+		}
+	}
+	for i, doc := range d.Documents {
+		if strings.HasSuffix(doc.Style, ".csl") && !filepath.IsAbs(doc.Style) {
+			d.Documents[i].Style = filepath.Join(filepath.Dir(filename), doc.Style)
+		}
+	}
+	return d, d.validate()
+}
+
+func (d *Data) validate() error {
+	var ds diagnostics
+	if len(d.Bibliographies) == 0 {
+		ds.add(d.file, 0, "no bibliographies specified")
+	}
+	for _, s := range d.Sections {
+		if s.Query == nil {
+			continue
+		}
+		for _, f := range s.Query.Files {
+			if !slices.Contains(d.Bibliographies, f) {
+				ds.add(d.file, s.Query.line, "section %s: query file %s is not one of the bibliographies", s.ID, f)
+			}
+		}
+	}
+	ids := make(map[string]int)
+	for i, s := range d.Sections {
+		if s.ID == "" {
+			ds.add(d.file, s.line, "section %d: missing id", i)
+		} else if prev, ok := ids[s.ID]; ok {
+			ds.add(d.file, s.line, "duplicate section id %s (previous at line %d)", s.ID, prev)
+		} else {
+			ids[s.ID] = s.line
+		}
+		s.validate(d.file, &ds)
+	}
+	d.validatePeople(&ds)
+	if len(d.Documents) == 0 {
+		ds.add(d.file, 0, "no documents specified")
+	}
+	docIDs := make(map[string]int)
+	outputs := make(map[string]int)
+	for i, doc := range d.Documents {
+		if doc.ID == "" {
+			ds.add(d.file, doc.line, "document %d: missing id", i)
+		} else if prev, ok := docIDs[doc.ID]; ok {
+			ds.add(d.file, doc.line, "duplicate document id %s (previous at line %d)", doc.ID, prev)
+		} else {
+			docIDs[doc.ID] = doc.line
+		}
+		if doc.Output == "" {
+			ds.add(d.file, doc.line, "document %s: missing output", doc.ID)
+		} else if prev, ok := outputs[doc.Output]; ok {
+			ds.add(d.file, doc.line, "document %s: duplicate output %s (previous at line %d)", doc.ID, doc.Output, prev)
+		} else {
+			outputs[doc.Output] = doc.line
+		}
+		if _, err := lookupStyle(doc.Style); err != nil {
+			ds.add(d.file, doc.line, "document %s: %v", doc.ID, err)
+		}
+		if err := checkLinkMode(doc.Links); err != nil {
+			ds.add(d.file, doc.line, "document %s: %v", doc.ID, err)
+		}
+		if len(doc.Sections) == 0 {
+			ds.add(d.file, doc.line, "document %s: no sections", doc.ID)
+		}
+		if err := doc.Print.validate(); err != nil {
+			ds.add(d.file, cmp.Or(doc.Print.line, doc.line), "document %s: print: %v", doc.ID, err)
+		}
+		if err := doc.LaTeX.validate(); err != nil {
+			ds.add(d.file, cmp.Or(doc.LaTeX.line, doc.line), "document %s: latex: %v", doc.ID, err)
+		}
+		for _, p := range []*PageText{doc.Header, doc.Footer} {
+			if p == nil {
+				continue
+			}
+			if err := p.validate(); err != nil {
+				ds.add(d.file, p.line, "document %s: %v", doc.ID, err)
+			}
+		}
+		if doc.Authors != nil {
+			if err := doc.Authors.validate(); err != nil {
+				ds.add(d.file, doc.Authors.line, "document %s: %v", doc.ID, err)
+			}
+		}
+		for _, ref := range doc.Sections {
+			// References to query sections are checked once the
+			// bibliographies have been read; see expand.
+			if s, ok := d.section(ref.Ref); ok && s.Query != nil {
+				continue
+			}
+			if _, err := d.resolveSection(ref); err != nil {
+				ds.add(d.file, ref.line, "document %s: %v", doc.ID, err)
+			}
+		}
+	}
+	return ds.err()
+}
+
+// marks returns what is marked on the names in citations.
+func (d *Data) marks() *nameMarks {
+	return &nameMarks{people: d.People, markers: &d.Markers}
+}
+
+// validatePeople checks the roles and people and links each person to
+// their role.
+func (d *Data) validatePeople(ds *diagnostics) {
+	roles := make(map[string]*Role)
+	for i := range d.Roles {
+		r := &d.Roles[i]
+		if r.ID == "" {
+			ds.add(d.file, r.line, "role %d: missing id", i)
+		} else if prev, ok := roles[r.ID]; ok {
+			ds.add(d.file, r.line, "duplicate role id %s (previous at line %d)", r.ID, prev.line)
+		} else {
+			roles[r.ID] = r
+		}
+	}
+	for i := range d.People {
+		p := &d.People[i]
+		if strings.TrimSpace(p.Name) == "" {
+			ds.add(d.file, p.line, "person %d: missing name", i)
+			continue
+		}
+		if p.Role == "" {
+			ds.add(d.file, p.line, "person %s: missing role", p.Name)
+		} else if r, ok := roles[p.Role]; !ok {
+			ds.add(d.file, p.line, "person %s: unknown role %s", p.Name, p.Role)
+		} else {
+			p.role = r
+		}
+		if p.From != 0 && p.To != 0 && p.From > p.To {
+			ds.add(d.file, p.line, "person %s: from (%d) is after to (%d)", p.Name, p.From, p.To)
+		}
+		p.names = nil
+		for j, s := range append([]string{p.Name}, p.Aliases...) {
+			n := parseName(s)
+			switch {
+			case n.Last != "":
+				p.names = append(p.names, n)
+			case j == 0:
+				ds.add(d.file, p.line, "person %d: blank name %q", i, s)
+			default:
+				ds.add(d.file, p.line, "person %s: blank alias %q", p.Name, s)
+			}
+		}
+	}
+}
+
+// expand fills in the citations of sections that are defined by a query,
+// in the order in which the entries appear in the bibliographies, sorts
+// the sections that request it, and checks the document references to
+// query sections.
+func (d *Data) expand(citations map[template.HTML]*bibEntry) error {
+	var ds diagnostics
+	keys := make([]template.HTML, 0, len(citations))
+	for k := range citations {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := citations[keys[i]], citations[keys[j]]
+		if a.File != b.File {
+			return slices.Index(d.Bibliographies, a.File) < slices.Index(d.Bibliographies, b.File)
+		}
+		return a.Line < b.Line
+	})
+	querySections := make(map[string]bool)
+	for i := range d.Sections {
+		s := &d.Sections[i]
+		if s.Query == nil {
+			continue
+		}
+		querySections[s.ID] = true
+		excluded := make(map[template.HTML]bool)
+		for _, k := range s.Exclude {
+			if _, ok := citations[k]; !ok {
+				ds.add(d.file, s.citationLines[k], "section %s: excluded citation key %s not found in bibliographies", s.ID, k)
+			}
+			excluded[k] = true
+		}
+		s.Citations = nil
+		for _, k := range keys {
+			if !excluded[k] && s.Query.match(citations[k]) {
+				s.Citations = append(s.Citations, k)
+			}
+		}
+		for _, k := range s.Include {
+			if !excluded[k] && !slices.Contains(s.Citations, k) {
+				s.Citations = append(s.Citations, k)
+			}
+		}
+		if len(s.Citations) == 0 {
+			ds.add(d.file, s.Query.line, "section %s: query matches no bibliography entries", s.ID)
+		}
+	}
+	for i := range d.Sections {
+		s := &d.Sections[i]
+		if s.Sort == nil {
+			continue
+		}
+		s.Sort.apply(s.Citations, citations)
+	}
+	for _, doc := range d.Documents {
+		for _, ref := range doc.Sections {
+			if !querySections[ref.Ref] {
+				continue
+			}
+			if _, err := d.resolveSection(ref); err != nil {
+				ds.add(d.file, ref.line, "document %s: %v", doc.ID, err)
+			}
+		}
+	}
+	return ds.err()
+}
+
+func (s Section) validate(file string, ds *diagnostics) {
+	if isBlank(s.Name) {
+		ds.add(file, s.line, "section %s: missing name", s.ID)
+	}
+	if len(s.Items) == 0 && len(s.Citations) == 0 && s.Query == nil {
+		ds.add(file, s.line, "section %s: no items or citations", s.ID)
+	}
+	if len(s.Items) > 0 && (len(s.Citations) > 0 || s.Query != nil) {
+		ds.add(file, s.line, "section %s: sections may have items or citations but not both", s.ID)
+	}
+	if len(s.Citations) > 0 && s.Query != nil {
+		ds.add(file, s.line, "section %s: sections may have a citation list or a query but not both", s.ID)
+	}
+	if s.Query == nil && (len(s.Include) > 0 || len(s.Exclude) > 0) {
+		ds.add(file, s.line, "section %s: include and exclude require a query", s.ID)
+	}
+	if s.Sort != nil {
+		if err := s.Sort.validate(); err != nil {
+			ds.add(file, s.Sort.line, "section %s: %v", s.ID, err)
+		}
+	}
+	if s.Authors != nil {
+		if err := s.Authors.validate(); err != nil {
+			ds.add(file, s.Authors.line, "section %s: %v", s.ID, err)
+		}
+	}
+	if s.JSONResume != "" && !slices.Contains(jsonResumeSections, s.JSONResume) {
+		ds.add(file, s.line, "section %s: unknown json-resume section '%s' (available: %s)", s.ID, s.JSONResume, strings.Join(jsonResumeSections, ", "))
+	} else if s.JSONResume == "publications" && len(s.Items) > 0 {
+		ds.add(file, s.line, "section %s: json-resume publications requires citations", s.ID)
+	} else if s.JSONResume != "" && s.JSONResume != "publications" && len(s.Items) == 0 {
+		ds.add(file, s.line, "section %s: json-resume %s requires items", s.ID, s.JSONResume)
+	}
+	if q := s.Query; q != nil && q.MinYear != 0 && q.MaxYear != 0 && q.MinYear > q.MaxYear {
+		ds.add(file, q.line, "section %s: min-year is after max-year", s.ID)
+	}
+	ids := make(map[string]bool)
+	for i, item := range s.Items {
+		if isBlank(item.Name) {
+			ds.add(file, item.line, "section %s: item %d: missing name", s.ID, i)
+		}
+		if item.ID == "" {
+			continue
+		}
+		if ids[item.ID] {
+			ds.add(file, item.line, "section %s: duplicate item id %s", s.ID, item.ID)
+		}
+		ids[item.ID] = true
+	}
+	seen := make(map[template.HTML]bool)
+	for i, c := range s.Citations {
+		if isBlank(c) {
+			ds.add(file, s.line, "section %s: citation %d: missing key", s.ID, i)
+		} else if seen[c] {
+			ds.add(file, s.citationLines[c], "section %s: citation %s listed more than once", s.ID, c)
+		}
+		seen[c] = true
+	}
+}
+
+// Document returns the document with the given ID.
+func (d *Data) Document(id string) (Document, error) {
+	for _, doc := range d.Documents {
+		if doc.ID == id {
+			return doc, nil
+		}
+	}
+	return Document{}, fmt.Errorf("no document with id %s", id)
+}
+
+func (d *Data) section(id string) (Section, bool) {
+	for _, s := range d.Sections {
+		if s.ID == id {
+			return s, true
+		}
+	}
+	return Section{}, false
+}
+
+// Resolve returns the sections of doc with their overrides applied.
+func (d *Data) Resolve(doc Document) ([]Section, error) {
+	if len(doc.Sections) == 0 {
+		return nil, fmt.Errorf("document %s: no sections", doc.ID)
+	}
+	out := make([]Section, len(doc.Sections))
+	for i, ref := range doc.Sections {
+		s, err := d.resolveSection(ref)
+		if err != nil {
+			return nil, fmt.Errorf("document %s: section %d: %v", doc.ID, i, err)
+		}
+		if s.Authors == nil {
+			s.Authors = doc.Authors
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+func (d *Data) resolveSection(ref SectionRef) (Section, error) {
+	s, ok := d.section(ref.Ref)
+	if !ok {
+		return s, fmt.Errorf("no section with id '%s'", ref.Ref)
+	}
+	if ref.Name != "" {
+		s.Name = ref.Name
+	}
+	if ref.Items != nil {
+		if len(s.Citations) > 0 {
+			return s, fmt.Errorf("%s: item subset given for a citation section", ref.Ref)
+		}
+		items := make([]Item, len(ref.Items))
+		for i, id := range ref.Items {
+			item, ok := findItem(s.Items, id)
+			if !ok {
+				return s, fmt.Errorf("%s: no item with id '%s'", ref.Ref, id)
+			}
+			items[i] = item
+		}
+		s.Items = items
+	}
+	if ref.Citations != nil {
+		if len(s.Items) > 0 {
+			return s, fmt.Errorf("%s: citation subset given for an item section", ref.Ref)
+		}
+		for _, c := range ref.Citations {
+			if !slices.Contains(s.Citations, c) {
+				return s, fmt.Errorf("%s: citation %s is not in the master section", ref.Ref, c)
+			}
+		}
+		if s.Sort != nil {
+			// Keep the sorted order of the master section.
+			s.Citations = slices.DeleteFunc(slices.Clone(s.Citations), func(c template.HTML) bool {
+				return !slices.Contains(ref.Citations, c)
+			})
+		} else {
+			s.Citations = ref.Citations
+		}
+	}
+	if ref.Max < 0 {
+		return s, fmt.Errorf("%s: negative max", ref.Ref)
+	}
+	if ref.Max > 0 && len(s.Items) > 0 {
+		return s, fmt.Errorf("%s: max given for an item section", ref.Ref)
+	}
+	if ref.Max > 0 && len(s.Citations) > ref.Max {
+		s.Citations = s.Citations[:ref.Max]
+	}
+	if ref.Authors != nil {
+		if len(s.Items) > 0 {
+			return s, fmt.Errorf("%s: authors given for an item section", ref.Ref)
+		}
+		if err := ref.Authors.validate(); err != nil {
+			return s, fmt.Errorf("%s: %v", ref.Ref, err)
+		}
+		s.Authors = ref.Authors
+	}
+	if len(s.Items) == 0 && len(s.Citations) == 0 {
+		return s, fmt.Errorf("%s: no items or citations", ref.Ref)
+	}
+	return s, nil
+}
+
+func findItem(items []Item, id string) (Item, bool) {
+	for _, item := range items {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return Item{}, false
+}
+
+func isBlank(s template.HTML) bool {
+	return strings.TrimSpace(string(s)) == ""
+}
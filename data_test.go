@@ -0,0 +1,204 @@
+package main
+
+import (
+	"html/template"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestQueryMatch(t *testing.T) {
+	citations := testBib(t, `
+@article{a, title = {A}, year = {2015}, keywords = {Air Quality; health, EJ}}
+@inproceedings{b, title = {B}, year = {2020}, keywords = {air quality}}
+@misc{c, title = {C}, year = {in press}}
+@article{d, title = {D}, date = {2018-04}}
+`)
+	file := citations["a"].File
+	for _, tt := range []struct {
+		name string
+		q    Query
+		want string // the keys that match, in order
+	}{
+		{"empty", Query{}, "abcd"},
+		{"type", Query{Types: stringList{"Article"}}, "ad"},
+		{"types", Query{Types: stringList{"misc", "inproceedings"}}, "bc"},
+		{"keyword", Query{Keywords: stringList{"air quality"}}, "ab"},
+		{"keywords", Query{Keywords: stringList{"health", "ej"}}, "a"},
+		{"partial keyword", Query{Keywords: stringList{"air"}}, ""},
+		{"file", Query{Files: stringList{file}}, "abcd"},
+		{"other file", Query{Files: stringList{"other.bib"}}, ""},
+		{"min year", Query{MinYear: 2018}, "bd"},
+		{"max year", Query{MaxYear: 2018}, "ad"},
+		{"year range", Query{MinYear: 2016, MaxYear: 2019}, "d"},
+		{"all", Query{Types: stringList{"article"}, Keywords: stringList{"EJ"}, MaxYear: 2015}, "a"},
+	} {
+		var got string
+		for _, k := range []template.HTML{"a", "b", "c", "d"} {
+			if tt.q.match(citations[k]) {
+				got += string(k)
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s: matched %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSortApply(t *testing.T) {
+	citations := testBib(t, `
+@article{b2020, author = {Young, B.}, title = {T}, year = {2020}}
+@article{A2020, author = {Zhu, A.}, title = {T}, year = {2020}}
+@article{may2020, author = {Xu, C.}, title = {T}, year = {2020}, month = may}
+@article{day2020, author = {Xu, C.}, title = {T}, year = {2020}, month = {5}, day = {20}}
+@article{iso2019, author = {Wu, D.}, title = {T}, date = {2019-12-31}}
+@article{press, author = {Vo, E.}, title = {T}, year = {in press}}
+@article{old, author = {Ung, F.}, title = {T}, year = {1999}}
+`)
+	keys := []template.HTML{"old", "b2020", "missing", "iso2019", "A2020", "press", "may2020", "day2020"}
+	for _, tt := range []struct {
+		name string
+		sort Sort
+		want []template.HTML
+	}{
+		{"desc", Sort{}, []template.HTML{"press", "day2020", "may2020", "A2020", "b2020", "iso2019", "old", "missing"}},
+		{"asc", Sort{Order: "asc"}, []template.HTML{"old", "iso2019", "A2020", "b2020", "may2020", "day2020", "press", "missing"}},
+		{"author", Sort{TieBreak: "author"}, []template.HTML{"press", "day2020", "may2020", "b2020", "A2020", "iso2019", "old", "missing"}},
+	} {
+		got := slices.Clone(keys)
+		tt.sort.apply(got, citations)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTruncationApply(t *testing.T) {
+	advisee := &Role{ID: "advisee"}
+	names := parseNames("A, A. and B, B. and C, C. and D, D. and E, E.")
+	names[3].Role = advisee
+	names[4].Markers = []*Marker{&defaultMarkers.Corresponding}
+	for _, tt := range []struct {
+		name string
+		t    *Truncation
+		in   []name
+		want string
+	}{
+		{"nil", nil, names, "ABCDE"},
+		{"show", &Truncation{Show: 2}, names, "AB+"},
+		{"short", &Truncation{Show: 5}, names, "ABCDE"},
+		{"max", &Truncation{Max: 5, Show: 1}, names, "ABCDE"},
+		{"over max", &Truncation{Max: 4, Show: 1}, names, "A+"},
+		{"highlighted", &Truncation{Show: 2, Highlighted: true}, names, "ABDE+"},
+		{"others", &Truncation{Show: 2}, parseNames("A, A. and B, B. and others"), "AB+"},
+		{"long others", &Truncation{Show: 1}, parseNames("A, A. and B, B. and others"), "A+"},
+	} {
+		var got string
+		for _, n := range tt.t.apply(tt.in) {
+			if n.isOthers() {
+				got += "+"
+			} else {
+				got += n.Last
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if names[2].Last != "C" {
+		t.Error("apply changed its argument")
+	}
+}
+
+func TestResolveSection(t *testing.T) {
+	d, _, err := load(filepath.Join("testdata", "sample.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	three := &Truncation{Show: 3}
+	for _, tt := range []struct {
+		name      string
+		ref       SectionRef
+		title     template.HTML
+		items     []string
+		citations []template.HTML
+		authors   *Truncation
+		err       string
+	}{
+		{name: "as is", ref: SectionRef{Ref: "work"}, title: "Appointments", items: []string{"prof", "postdoc"}},
+		{name: "renamed", ref: SectionRef{Ref: "work", Name: "Positions"}, title: "Positions", items: []string{"prof", "postdoc"}},
+		{name: "item subset", ref: SectionRef{Ref: "work", Items: []string{"postdoc"}}, title: "Appointments", items: []string{"postdoc"}},
+		{name: "item order", ref: SectionRef{Ref: "work", Items: []string{"postdoc", "prof"}}, title: "Appointments", items: []string{"postdoc", "prof"}},
+		{name: "sorted subset", ref: SectionRef{Ref: "publications", Citations: []template.HTML{"roe2019model", "doe2021air"}},
+			title: "Publications", citations: []template.HTML{"doe2021air", "roe2019model"}},
+		{name: "unsorted subset", ref: SectionRef{Ref: "other", Citations: []template.HTML{"doe2019talk", "doe2022preprint"}},
+			title: "Presentations and Preprints", citations: []template.HTML{"doe2019talk", "doe2022preprint"}},
+		{name: "max", ref: SectionRef{Ref: "publications", Max: 1}, title: "Publications", citations: []template.HTML{"doe2021air"}},
+		{name: "max over length", ref: SectionRef{Ref: "publications", Max: 5}, title: "Publications", citations: []template.HTML{"doe2021air", "roe2019model"}},
+		{name: "authors", ref: SectionRef{Ref: "other", Authors: three}, title: "Presentations and Preprints",
+			citations: []template.HTML{"doe2022preprint", "doe2019talk"}, authors: three},
+
+		{name: "unknown ref", ref: SectionRef{Ref: "nope"}, err: "no section with id 'nope'"},
+		{name: "unknown item", ref: SectionRef{Ref: "work", Items: []string{"nope"}}, err: "work: no item with id 'nope'"},
+		{name: "items of citations", ref: SectionRef{Ref: "publications", Items: []string{"prof"}}, err: "publications: item subset given for a citation section"},
+		{name: "unknown citation", ref: SectionRef{Ref: "publications", Citations: []template.HTML{"doe2019talk"}},
+			err: "publications: citation doe2019talk is not in the master section"},
+		{name: "citations of items", ref: SectionRef{Ref: "work", Citations: []template.HTML{"doe2021air"}}, err: "work: citation subset given for an item section"},
+		{name: "empty subset", ref: SectionRef{Ref: "work", Items: []string{}}, err: "work: no items or citations"},
+		{name: "negative max", ref: SectionRef{Ref: "publications", Max: -1}, err: "publications: negative max"},
+		{name: "max of items", ref: SectionRef{Ref: "work", Max: 1}, err: "work: max given for an item section"},
+		{name: "authors of items", ref: SectionRef{Ref: "work", Authors: three}, err: "work: authors given for an item section"},
+		{name: "invalid authors", ref: SectionRef{Ref: "publications", Authors: &Truncation{Show: 0}}, err: "publications: authors: show must be at least 1"},
+	} {
+		s, err := d.resolveSection(tt.ref)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var items []string
+		for _, item := range s.Items {
+			items = append(items, item.ID)
+		}
+		if s.Name != tt.title || !slices.Equal(items, tt.items) || !slices.Equal(s.Citations, tt.citations) || s.Authors != tt.authors {
+			t.Errorf("%s: got %q, items %q, citations %q, authors %v; want %q, %q, %q, %v",
+				tt.name, s.Name, items, s.Citations, s.Authors, tt.title, tt.items, tt.citations, tt.authors)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	d, _, err := load(filepath.Join("testdata", "sample.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := d.Documents[1]
+	sections, err := d.Resolve(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 2 || sections[0].ID != "work" || sections[1].Name != "Selected Publications" {
+		t.Fatalf("got sections %+v", sections)
+	}
+	// The document's author truncation applies to sections that set none.
+	if sections[1].Authors != doc.Authors {
+		t.Errorf("authors %v, want the document's %v", sections[1].Authors, doc.Authors)
+	}
+	// Resolving does not change the master sections.
+	if s, _ := d.section("publications"); s.Name != "Publications" || s.Authors != nil {
+		t.Errorf("master section changed to %+v", s)
+	}
+
+	if _, err := d.Resolve(Document{ID: "empty"}); err == nil || err.Error() != "document empty: no sections" {
+		t.Errorf("empty document: got %v", err)
+	}
+	bad := Document{ID: "bad", Sections: []SectionRef{{Ref: "work"}, {Ref: "nope"}}}
+	if _, err := d.Resolve(bad); err == nil || err.Error() != "document bad: section 1: no section with id 'nope'" {
+		t.Errorf("unknown section: got %v", err)
+	}
+}
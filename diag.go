@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A diagnostic is a problem found in one of the input files.
+type diagnostic struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (d diagnostic) String() string {
+	switch {
+	case d.File == "":
+		return d.Msg
+	case d.Line == 0:
+		return fmt.Sprintf("%s: %s", d.File, d.Msg)
+	default:
+		return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Msg)
+	}
+}
+
+// diagnostics collects problems so that they can all be reported at once
+// rather than stopping at the first one.
+type diagnostics []diagnostic
+
+func (ds *diagnostics) add(file string, line int, format string, args ...interface{}) {
+	*ds = append(*ds, diagnostic{File: file, Line: line, Msg: fmt.Sprintf(format, args...)})
+}
+
+// merge adds err to ds, expanding it if it is itself a set of diagnostics.
+func (ds *diagnostics) merge(err error) {
+	var other diagnostics
+	switch {
+	case err == nil:
+	case errors.As(err, &other):
+		*ds = append(*ds, other...)
+	default:
+		ds.add("", 0, "%v", err)
+	}
+}
+
+// err returns ds as an error, or nil if there are no diagnostics.
+func (ds diagnostics) err() error {
+	if len(ds) == 0 {
+		return nil
+	}
+	return ds
+}
+
+func (ds diagnostics) Error() string {
+	sorted := make(diagnostics, len(ds))
+	copy(sorted, ds)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].File != sorted[j].File {
+			return sorted[i].File < sorted[j].File
+		}
+		return sorted[i].Line < sorted[j].Line
+	})
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d problem", len(ds))
+	if len(ds) != 1 {
+		b.WriteString("s")
+	}
+	b.WriteString(" found:")
+	for _, d := range sorted {
+		b.WriteString("\n\t")
+		b.WriteString(d.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDiagnosticsMerge(t *testing.T) {
+	var inner diagnostics
+	inner.add("b.bib", 3, "missing %s", "title")
+	inner.add("a.yaml", 0, "no documents")
+
+	for _, tt := range []struct {
+		name string
+		errs []error
+		want string
+	}{
+		{"none", nil, ""},
+		{"nil", []error{nil}, ""},
+		{"plain", []error{errors.New("boom")}, "1 problem found:\n\tboom"},
+		{"expanded", []error{inner}, "2 problems found:\n\ta.yaml: no documents\n\tb.bib:3: missing title"},
+		{"wrapped", []error{fmt.Errorf("loading: %w", inner), errors.New("boom")},
+			"3 problems found:\n\tboom\n\ta.yaml: no documents\n\tb.bib:3: missing title"},
+	} {
+		var ds diagnostics
+		for _, err := range tt.errs {
+			ds.merge(err)
+		}
+		err := ds.err()
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: got %v, want no error", tt.name, err)
+		case tt.want != "" && (err == nil || err.Error() != tt.want):
+			t.Errorf("%s:\n got %v\nwant %s", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestDiagnosticsSorted(t *testing.T) {
+	var ds diagnostics
+	ds.add("b.yaml", 2, "second")
+	ds.add("a.yaml", 9, "first")
+	ds.add("b.yaml", 1, "also first")
+	want := "3 problems found:\n\ta.yaml:9: first\n\tb.yaml:1: also first\n\tb.yaml:2: second"
+	if got := ds.Error(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if ds[0].Msg != "second" {
+		t.Error("Error reordered the diagnostics it reports")
+	}
+}
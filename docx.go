@@ -0,0 +1,318 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Layout of DOCX documents written by writeDOCX, in points at a scale of 1.
+const (
+	docxFont        = "Times New Roman"
+	docxBodySize    = 11
+	docxHeadingSize = 14
+	docxNameSize    = 24
+	docxContactSize = 10.5
+	docxPageSize    = 9 // page headers and footers
+)
+
+// writeDOCX writes r as a Microsoft Word document.
+func writeDOCX(r *rendering, filename string) error {
+	w := &docxWriter{r: r}
+	body, err := w.body()
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	z := zip.NewWriter(&b)
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRootRels},
+		{"docProps/core.xml", w.coreProperties()},
+		{"word/styles.xml", w.styles()},
+		{"word/document.xml", docxDocumentStart + body + w.sectionProperties() + docxDocumentEnd},
+		{"word/header1.xml", w.pageText("hdr", r.Header)},
+		{"word/footer1.xml", w.pageText("ftr", r.Footer)},
+		{"word/_rels/document.xml.rels", w.relationships()},
+	}
+	for _, f := range files {
+		fw, err := z.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write([]byte(xml.Header + f.content)); err != nil {
+			return err
+		}
+	}
+	if err := z.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b.Bytes(), 0644)
+}
+
+const (
+	docxContentTypes = `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+		`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+		`<Override PartName="/word/header1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml"/>` +
+		`<Override PartName="/word/footer1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footer+xml"/>` +
+		`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>` +
+		`</Types>`
+	docxRootRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>` +
+		`</Relationships>`
+	docxNamespaces = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	docxDocumentStart = `<w:document ` + docxNamespaces + `><w:body>`
+	docxDocumentEnd   = `</w:body></w:document>`
+)
+
+// docxWriter writes the parts of a CV as WordprocessingML.
+type docxWriter struct {
+	r     *rendering
+	links []string // targets of hyperlinks, by relationship number
+}
+
+// twips returns v points at the scale of the document in twentieths of a
+// point, the unit of most lengths in WordprocessingML.
+func (w *docxWriter) twips(v float64) int {
+	return int(v * w.r.Print.Scale * 20)
+}
+
+// halfPoints returns the font size v points at the scale of the document
+// in half points.
+func (w *docxWriter) halfPoints(v float64) int {
+	return int(v * w.r.Print.Scale * 2)
+}
+
+// width returns the width between the margins in twips.
+func (w *docxWriter) width() int {
+	pageWidth, _ := w.r.Print.size()
+	m := w.r.Print.Margins
+	return int((pageWidth - 72*(m.Left+m.Right)) * 20)
+}
+
+func (w *docxWriter) body() (string, error) {
+	r := w.r
+	var b strings.Builder
+	fmt.Fprintf(&b, `<w:p><w:pPr><w:pStyle w:val="Name"/></w:pPr>%s</w:p>`, w.runs([]textRun{{Text: r.Owner.Name}}, docxNameSize))
+	lines := r.Owner.contactLines()
+	for i, l := range lines {
+		border := ""
+		if i == len(lines)-1 {
+			border = `<w:pBdr><w:bottom w:val="single" w:sz="4" w:space="6" w:color="C8C8C8"/></w:pBdr>`
+		}
+		fmt.Fprintf(&b, `<w:p><w:pPr><w:pStyle w:val="Contact"/>%s</w:pPr>%s</w:p>`, border, w.runs(parseFragment(l), docxContactSize))
+	}
+	for _, s := range r.Sections {
+		if err := w.section(&b, s); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// section writes the heading and contents of s, indented as in the HTML
+// template.
+func (w *docxWriter) section(b *strings.Builder, s Section) error {
+	r := w.r
+	indent := w.width() / 12
+	fmt.Fprintf(b, `<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr>%s%s</w:p>`,
+		w.runs(parseFragment(string(s.Name)), docxHeadingSize), w.runs(parseFragment(string(r.legend(s.Citations, s.Authors))), docxHeadingSize))
+
+	var groups []citationGroup
+	if len(s.Citations) > 0 {
+		groups = r.groups(s)
+	}
+	for _, g := range groups {
+		if s.GroupByYear {
+			fmt.Fprintf(b, `<w:p><w:pPr><w:pStyle w:val="Year"/><w:ind w:left="%d"/></w:pPr>%s</w:p>`,
+				indent, w.runs([]textRun{{Text: g.Year}}, docxBodySize))
+		}
+		for i, key := range g.Citations {
+			text, err := r.ref(key, s.Authors)
+			if err != nil {
+				return err
+			}
+			// Word cannot number a list downwards, so the numbers are
+			// written out, hanging in the indentation.
+			number := w.runs([]textRun{{Text: fmt.Sprintf("%d.", g.Start-i)}}, docxBodySize)
+			fmt.Fprintf(b, `<w:p><w:pPr><w:pStyle w:val="Item"/><w:tabs><w:tab w:val="right" w:pos="%d"/><w:tab w:val="left" w:pos="%d"/></w:tabs><w:ind w:left="%d" w:hanging="%d"/></w:pPr><w:r><w:tab/></w:r>%s<w:r><w:tab/></w:r>%s</w:p>`,
+				indent-w.twips(4), indent, indent, indent, number, w.runs(parseFragment(string(text)), docxBodySize))
+		}
+	}
+
+	for _, item := range s.Items {
+		var time string
+		if item.Time != "" {
+			time = `<w:r><w:tab/></w:r>` + w.runs(parseFragment(string(item.Time)), docxBodySize)
+		}
+		var description string
+		if item.Description != "" {
+			description = `<w:r><w:br/></w:r>` + w.runs(parseFragment(string(item.Description)), docxBodySize)
+		}
+		fmt.Fprintf(b, `<w:p><w:pPr><w:pStyle w:val="Item"/><w:tabs><w:tab w:val="right" w:pos="%d"/></w:tabs><w:ind w:left="%d"/></w:pPr>%s%s%s</w:p>`,
+			w.width(), indent, w.runs(parseFragment(string(item.Name)), docxBodySize), time, description)
+	}
+	return nil
+}
+
+// runs converts runs in a paragraph of the given font size to
+// WordprocessingML, adding a relationship for each hyperlink.
+func (w *docxWriter) runs(runs []textRun, size float64) string {
+	var b strings.Builder
+	for _, run := range runs {
+		if run.Break {
+			b.WriteString(`<w:r><w:br/></w:r>`)
+			continue
+		}
+		var props strings.Builder
+		if run.Bold {
+			props.WriteString(`<w:b/>`)
+		}
+		if run.Italic {
+			props.WriteString(`<w:i/>`)
+		}
+		if run.Href != "" {
+			props.WriteString(`<w:color w:val="0066CC"/>`)
+		}
+		if run.Small {
+			fmt.Fprintf(&props, `<w:sz w:val="%d"/>`, w.halfPoints(size*0.85))
+		}
+		if run.Underline {
+			props.WriteString(`<w:u w:val="single"/>`)
+		}
+		switch {
+		case run.Sup:
+			props.WriteString(`<w:vertAlign w:val="superscript"/>`)
+		case run.Sub:
+			props.WriteString(`<w:vertAlign w:val="subscript"/>`)
+		}
+		r := fmt.Sprintf(`<w:r><w:t xml:space="preserve">%s</w:t></w:r>`, escapeXML(run.Text))
+		if props.Len() > 0 {
+			r = fmt.Sprintf(`<w:r><w:rPr>%s</w:rPr><w:t xml:space="preserve">%s</w:t></w:r>`, props.String(), escapeXML(run.Text))
+		}
+		if run.Href != "" {
+			w.links = append(w.links, run.Href)
+			r = fmt.Sprintf(`<w:hyperlink r:id="link%d">%s</w:hyperlink>`, len(w.links), r)
+		}
+		b.WriteString(r)
+	}
+	return b.String()
+}
+
+// pageText returns the header or footer part of the document, of element
+// hdr or ftr, showing p, if set, with its parts aligned at tab stops.
+func (w *docxWriter) pageText(element string, p *PageText) string {
+	var content string
+	if p != nil {
+		text := func(s string) string {
+			if s == "" {
+				return ""
+			}
+			return fmt.Sprintf(`<w:r><w:t xml:space="preserve">%s</w:t></w:r>`, escapeXML(s))
+		}
+		values := w.r.pageValues(`<w:fldSimple w:instr="PAGE"><w:r><w:t>1</w:t></w:r></w:fldSimple>`,
+			`<w:fldSimple w:instr="NUMPAGES"><w:r><w:t>1</w:t></w:r></w:fldSimple>`, text)
+		var parts []string
+		for _, s := range p.parts() {
+			parts = append(parts, expandPage(s, values, text))
+		}
+		content = strings.Join(parts, `<w:r><w:tab/></w:r>`)
+	}
+	return fmt.Sprintf(`<w:%s %s><w:p><w:pPr><w:pStyle w:val="PageText"/><w:tabs><w:tab w:val="center" w:pos="%d"/><w:tab w:val="right" w:pos="%d"/></w:tabs></w:pPr>%s</w:p></w:%s>`,
+		element, docxNamespaces, w.width()/2, w.width(), content, element)
+}
+
+// sectionProperties returns the page setup of the document.
+func (w *docxWriter) sectionProperties() string {
+	p := w.r.Print
+	width, height := p.size()
+	orient := "portrait"
+	if p.Landscape {
+		orient = "landscape"
+	}
+	inch := func(v float64) int { return int(v * 1440) }
+	m := p.Margins
+	// The header and footer are centered in the top and bottom margins.
+	return fmt.Sprintf(`<w:sectPr><w:headerReference w:type="default" r:id="header"/><w:footerReference w:type="default" r:id="footer"/>`+
+		`<w:pgSz w:w="%d" w:h="%d" w:orient="%s"/>`+
+		`<w:pgMar w:top="%d" w:bottom="%d" w:left="%d" w:right="%d" w:header="%d" w:footer="%d" w:gutter="0"/></w:sectPr>`,
+		int(width*20), int(height*20), orient,
+		inch(m.Top), inch(m.Bottom), inch(m.Left), inch(m.Right),
+		inch(m.Top/2)-w.twips(docxPageSize)/2, inch(m.Bottom/2)-w.twips(docxPageSize)/2)
+}
+
+// relationships returns the relationships of the main document part to
+// the other parts and to the targets of its hyperlinks.
+func (w *docxWriter) relationships() string {
+	var b strings.Builder
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	b.WriteString(`<Relationship Id="styles" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`)
+	b.WriteString(`<Relationship Id="header" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/header" Target="header1.xml"/>`)
+	b.WriteString(`<Relationship Id="footer" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/footer" Target="footer1.xml"/>`)
+	for i, l := range w.links {
+		fmt.Fprintf(&b, `<Relationship Id="link%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="%s" TargetMode="External"/>`,
+			i+1, escapeXML(l))
+	}
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+// styles returns the paragraph styles used by the document.
+func (w *docxWriter) styles() string {
+	style := func(id, props, runProps string) string {
+		return fmt.Sprintf(`<w:style w:type="paragraph" w:styleId="%s"><w:name w:val="%s"/><w:basedOn w:val="Normal"/><w:pPr>%s</w:pPr><w:rPr>%s</w:rPr></w:style>`,
+			id, id, props, runProps)
+	}
+	size := func(v float64) string { return fmt.Sprintf(`<w:sz w:val="%d"/>`, w.halfPoints(v)) }
+	spacing := func(before, after float64) string {
+		return fmt.Sprintf(`<w:spacing w:before="%d" w:after="%d"/>`, w.twips(before), w.twips(after))
+	}
+	return `<w:styles ` + docxNamespaces + `>` +
+		fmt.Sprintf(`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="%s" w:hAnsi="%s" w:cs="%s"/>%s</w:rPr></w:rPrDefault>`+
+			`<w:pPrDefault><w:pPr>%s</w:pPr></w:pPrDefault></w:docDefaults>`,
+			docxFont, docxFont, docxFont, size(docxBodySize), spacing(0, 0)) +
+		`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/></w:style>` +
+		style("Name", "", size(docxNameSize)) +
+		style("Contact", spacing(0, 6)+`<w:jc w:val="right"/>`, size(docxContactSize)) +
+		style("Heading1", `<w:keepNext/>`+spacing(12, 6)+`<w:outlineLvl w:val="0"/>`, `<w:smallCaps/>`+size(docxHeadingSize)) +
+		style("Year", `<w:keepNext/>`+spacing(0, 4), `<w:b/>`) +
+		style("Item", spacing(0, 6), "") +
+		style("PageText", "", `<w:color w:val="646464"/>`+size(docxPageSize)) +
+		`</w:styles>`
+}
+
+// coreProperties returns the metadata of the document.
+func (w *docxWriter) coreProperties() string {
+	names := map[string]string{
+		"Title":    "dc:title",
+		"Author":   "dc:creator",
+		"Subject":  "dc:subject",
+		"Keywords": "cp:keywords",
+	}
+	var b strings.Builder
+	b.WriteString(`<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" ` +
+		`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">`)
+	for _, e := range w.r.pdfInfo() {
+		fmt.Fprintf(&b, `<%s>%s</%s>`, names[e[0]], escapeXML(e[1]), names[e[0]])
+	}
+	fmt.Fprintf(&b, `<dcterms:created xsi:type="dcterms:W3CDTF">%s</dcterms:created>`, w.r.Date.UTC().Format("2006-01-02T15:04:05Z"))
+	b.WriteString(`</cp:coreProperties>`)
+	return b.String()
+}
+
+// escapeXML escapes s for use as XML text or an attribute value.
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestWriteDOCX(t *testing.T) {
+	for _, r := range testRenderings(t) {
+		b := export(t, r, writeDOCX)
+		z, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts := make(map[string][]byte)
+		for _, f := range z.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			part, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts[f.Name] = part
+			// Word refuses documents with malformed parts.
+			d := xml.NewDecoder(bytes.NewReader(part))
+			for {
+				if _, err := d.Token(); errors.Is(err, io.EOF) {
+					break
+				} else if err != nil {
+					t.Errorf("%s: %s: %v", r.ID, f.Name, err)
+					break
+				}
+			}
+		}
+		for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/_rels/document.xml.rels", "docProps/core.xml", "word/styles.xml", "word/header1.xml", "word/footer1.xml"} {
+			if parts[name] == nil {
+				t.Errorf("%s: missing part %s", r.ID, name)
+			}
+		}
+		checkGolden(t, r.ID+".docx.xml", parts["word/document.xml"])
+	}
+}
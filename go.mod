@@ -3,7 +3,10 @@ module bitbucket.org/ctessum/cv
 require (
 	github.com/chromedp/cdproto v0.0.0-20250224005500-01948a15fe7c
 	github.com/chromedp/chromedp v0.13.0
+	github.com/go-pdf/fpdf v0.9.0
 	github.com/nickng/bibtex v1.4.0
+	golang.org/x/text v0.22.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,559 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// jsonResumeSections are the sections of the JSON Resume schema
+// (https://jsonresume.org/schema) that a section can be exported to.
+// Citation sections are exported as publications and item sections are
+// only exported if they name one of the others.
+var jsonResumeSections = []string{"work", "volunteer", "education", "awards", "publications", "projects", "skills", "languages"}
+
+// jsonResumeNames are the names given to the sections of an imported
+// resume.
+var jsonResumeNames = map[string]string{
+	"work":         "Professional Experience",
+	"volunteer":    "Volunteer Experience",
+	"education":    "Education",
+	"awards":       "Honors and Awards",
+	"publications": "Publications",
+	"projects":     "Projects",
+	"skills":       "Skills",
+	"languages":    "Languages",
+}
+
+// jsonResume is a resume in the JSON Resume schema, limited to the parts
+// that correspond to the CV data.
+type jsonResume struct {
+	Basics       jsonResumeBasics        `json:"basics"`
+	Work         []jsonResumeWork        `json:"work,omitempty"`
+	Volunteer    []jsonResumeWork        `json:"volunteer,omitempty"`
+	Education    []jsonResumeEducation   `json:"education,omitempty"`
+	Awards       []jsonResumeAward       `json:"awards,omitempty"`
+	Publications []jsonResumePublication `json:"publications,omitempty"`
+	Projects     []jsonResumeProject     `json:"projects,omitempty"`
+	Skills       []jsonResumeSkill       `json:"skills,omitempty"`
+	Languages    []jsonResumeLanguage    `json:"languages,omitempty"`
+}
+
+type jsonResumeBasics struct {
+	Name     string              `json:"name,omitempty"`
+	Email    string              `json:"email,omitempty"`
+	URL      string              `json:"url,omitempty"`
+	Profiles []jsonResumeProfile `json:"profiles,omitempty"`
+}
+
+type jsonResumeProfile struct {
+	Network  string `json:"network,omitempty"`
+	Username string `json:"username,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// jsonResumeWork is a work or volunteer entry. The schema names the
+// employer of work Name and the organization of volunteering
+// Organization.
+type jsonResumeWork struct {
+	Name         string   `json:"name,omitempty"`
+	Organization string   `json:"organization,omitempty"`
+	Position     string   `json:"position,omitempty"`
+	URL          string   `json:"url,omitempty"`
+	StartDate    string   `json:"startDate,omitempty"`
+	EndDate      string   `json:"endDate,omitempty"`
+	Summary      string   `json:"summary,omitempty"`
+	Highlights   []string `json:"highlights,omitempty"`
+}
+
+type jsonResumeEducation struct {
+	Institution string `json:"institution,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Area        string `json:"area,omitempty"`
+	StudyType   string `json:"studyType,omitempty"`
+	StartDate   string `json:"startDate,omitempty"`
+	EndDate     string `json:"endDate,omitempty"`
+}
+
+type jsonResumeAward struct {
+	Title   string `json:"title,omitempty"`
+	Date    string `json:"date,omitempty"`
+	Awarder string `json:"awarder,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+type jsonResumePublication struct {
+	Name        string `json:"name,omitempty"`
+	Publisher   string `json:"publisher,omitempty"`
+	ReleaseDate string `json:"releaseDate,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Summary     string `json:"summary,omitempty"`
+}
+
+type jsonResumeProject struct {
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Highlights  []string `json:"highlights,omitempty"`
+	StartDate   string   `json:"startDate,omitempty"`
+	EndDate     string   `json:"endDate,omitempty"`
+	URL         string   `json:"url,omitempty"`
+}
+
+type jsonResumeSkill struct {
+	Name     string   `json:"name,omitempty"`
+	Level    string   `json:"level,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+type jsonResumeLanguage struct {
+	Language string `json:"language,omitempty"`
+	Fluency  string `json:"fluency,omitempty"`
+}
+
+// writeJSONResume writes r in the JSON Resume schema.
+func writeJSONResume(r *rendering, filename string) error {
+	res, err := r.jsonResume()
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(res); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b.Bytes(), 0644)
+}
+
+// jsonResume converts r to the JSON Resume schema. Items are split into
+// the fields of the schema by splitItemName and parseItemTime, and all
+// text is converted to plain text.
+func (r *rendering) jsonResume() (*jsonResume, error) {
+	o := r.Owner
+	res := &jsonResume{Basics: jsonResumeBasics{Name: o.Name, Email: o.Email, URL: o.Website}}
+	if o.ORCID != "" {
+		res.Basics.Profiles = append(res.Basics.Profiles, jsonResumeProfile{
+			Network: "ORCID", Username: o.ORCID, URL: "https://orcid.org/" + o.ORCID,
+		})
+	}
+	text := func(h template.HTML) string { return plainText(parseFragment(string(h))) }
+	for _, s := range r.Sections {
+		for _, key := range s.Citations {
+			p, err := r.jsonResumePublication(key, s.Authors)
+			if err != nil {
+				return nil, err
+			}
+			res.Publications = append(res.Publications, p)
+		}
+		for _, item := range s.Items {
+			name, at := splitItemName(text(item.Name))
+			url := firstLink(parseFragment(string(item.Name)))
+			start, end := parseItemTime(text(item.Time))
+			description := text(item.Description)
+			switch s.JSONResume {
+			case "work":
+				res.Work = append(res.Work, jsonResumeWork{Name: at, Position: name, URL: url, StartDate: start, EndDate: end, Summary: description})
+			case "volunteer":
+				res.Volunteer = append(res.Volunteer, jsonResumeWork{Organization: at, Position: name, URL: url, StartDate: start, EndDate: end, Summary: description})
+			case "education":
+				studyType, area, _ := strings.Cut(name, ", ")
+				res.Education = append(res.Education, jsonResumeEducation{Institution: at, URL: url, StudyType: studyType, Area: area, StartDate: start, EndDate: end})
+			case "awards":
+				res.Awards = append(res.Awards, jsonResumeAward{Title: name, Awarder: at, Date: start, Summary: description})
+			case "projects":
+				res.Projects = append(res.Projects, jsonResumeProject{Name: joinItemName(name, at), Description: description, StartDate: start, EndDate: end, URL: url})
+			case "skills":
+				// Items list keywords, after a name if they have one.
+				skill, keywords := name, at
+				if at == "" {
+					skill, keywords = text(s.Name), name
+				}
+				res.Skills = append(res.Skills, jsonResumeSkill{Name: skill, Level: description, Keywords: splitKeywords(keywords)})
+			case "languages":
+				res.Languages = append(res.Languages, jsonResumeLanguage{Language: joinItemName(name, at), Fluency: description})
+			}
+		}
+	}
+	return res, nil
+}
+
+// jsonResumePublication converts the citation with the given key. Its
+// summary is the citation as formatted by the document's style.
+func (r *rendering) jsonResumePublication(key template.HTML, authors *Truncation) (jsonResumePublication, error) {
+	e, ok := r.citations[key]
+	if !ok {
+		return jsonResumePublication{}, fmt.Errorf("invalid citation key %s", key)
+	}
+	ref, err := r.ref(key, authors)
+	if err != nil {
+		return jsonResumePublication{}, err
+	}
+	p := jsonResumePublication{
+		Name:    plainText(parseFragment(e.field("title"))),
+		URL:     e.linkURL(),
+		Summary: plainText(parseFragment(string(ref))),
+	}
+	for _, f := range []string{"journal", "booktitle", "publisher", "institution", "school", "howpublished"} {
+		if e.has(f) {
+			p.Publisher = plainText(parseFragment(e.field(f)))
+			break
+		}
+	}
+	// Years such as "in press" have no release date.
+	if d := entryDate(e); d[0] > 0 && d[0] < 10000 {
+		p.ReleaseDate = fmt.Sprintf("%04d", d[0])
+		if d[1] > 0 {
+			p.ReleaseDate += fmt.Sprintf("-%02d", d[1])
+			if d[2] > 0 {
+				p.ReleaseDate += fmt.Sprintf("-%02d", d[2])
+			}
+		}
+	}
+	return p, nil
+}
+
+// firstLink returns the address of the first link in runs, if any.
+func firstLink(runs []textRun) string {
+	for _, run := range runs {
+		if run.Href != "" {
+			return run.Href
+		}
+	}
+	return ""
+}
+
+// splitItemName splits an item name such as "Research Scientist—University
+// of Washington" or "Aerodynamics Intern: Volvo Car Corporation" into the
+// position, degree or title and the organization.
+func splitItemName(s string) (name, at string) {
+	if i := strings.LastIndex(s, "—"); i >= 0 {
+		return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+len("—"):])
+	}
+	if name, at, ok := strings.Cut(s, ": "); ok {
+		return strings.TrimSpace(name), strings.TrimSpace(at)
+	}
+	return s, ""
+}
+
+// joinItemName is the inverse of splitItemName.
+func joinItemName(name, at string) string {
+	if name == "" || at == "" {
+		return name + at
+	}
+	return name + "—" + at
+}
+
+// matchItemDate matches an ISO 8601 date or partial date.
+var matchItemDate = regexp.MustCompile(`\d{4}(-\d{2}(-\d{2})?)?`)
+
+// parseItemTime returns the start and end dates of an item's time, such as
+// "2016–2019", "Fall 2020–Present" or "2013". Times that have not ended
+// have no end date.
+func parseItemTime(s string) (start, end string) {
+	from, to, ok := strings.Cut(s, "–")
+	start = matchItemDate.FindString(from)
+	if !ok {
+		return start, start
+	}
+	return start, matchItemDate.FindString(to)
+}
+
+// formatItemTime is the inverse of parseItemTime, showing only the years.
+// Items with a start date and no end date are ongoing.
+func formatItemTime(start, end string) string {
+	start, _, _ = strings.Cut(start, "-")
+	end, _, _ = strings.Cut(end, "-")
+	switch {
+	case start == "":
+		return end
+	case end == "":
+		return start + "–present"
+	case start == end:
+		return start
+	}
+	return start + "–" + end
+}
+
+// splitKeywords splits a list separated by semicolons, as in the
+// programming languages of a resume.
+func splitKeywords(s string) []string {
+	var keywords []string
+	for _, k := range strings.Split(s, ";") {
+		if k = strings.TrimSpace(k); k != "" {
+			keywords = append(keywords, k)
+		}
+	}
+	return keywords
+}
+
+// importedData is a data file created from a JSON Resume, written with
+// only the fields that are set.
+type importedData struct {
+	Owner          importedOwner      `yaml:"owner"`
+	Bibliographies []string           `yaml:"bibliographies,omitempty,flow"`
+	Sections       []importedSection  `yaml:"sections"`
+	Documents      []importedDocument `yaml:"documents"`
+}
+
+type importedOwner struct {
+	Name    string `yaml:"name"`
+	Email   string `yaml:"email,omitempty"`
+	Website string `yaml:"website,omitempty"`
+	ORCID   string `yaml:"orcid,omitempty"`
+}
+
+type importedSection struct {
+	ID         string         `yaml:"id"`
+	Name       string         `yaml:"name"`
+	JSONResume string         `yaml:"json-resume,omitempty"`
+	Items      []importedItem `yaml:"items,omitempty"`
+	Citations  []string       `yaml:"citations,omitempty,flow"`
+}
+
+type importedItem struct {
+	Name        string `yaml:"name"`
+	Time        string `yaml:"time,omitempty"`
+	Description string `yaml:"description,omitempty"`
+}
+
+type importedDocument struct {
+	ID       string   `yaml:"id"`
+	Output   string   `yaml:"output"`
+	Sections []string `yaml:"sections,flow"`
+}
+
+// importJSONResume creates a data file from the JSON Resume in filename,
+// with a document "cv" that shows every section, and returns the names of
+// the files written. Publications are written to a bibliography with the
+// same name as the data file, which defaults to the person's name followed
+// by _CV.yaml. Existing files are not overwritten.
+func importJSONResume(filename, dataFile string) ([]string, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var res jsonResume
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if strings.TrimSpace(res.Basics.Name) == "" {
+		return nil, fmt.Errorf("%s: missing basics.name", filename)
+	}
+	if dataFile == "" {
+		words := strings.FieldsFunc(res.Basics.Name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		dataFile = strings.Join(append(words, "CV"), "_") + ".yaml"
+	}
+	d := importedData{Owner: importedOwner{Name: res.Basics.Name, Email: res.Basics.Email, Website: res.Basics.URL}}
+	for _, p := range res.Basics.Profiles {
+		if strings.EqualFold(p.Network, "ORCID") {
+			d.Owner.ORCID = cmp.Or(p.Username, strings.TrimPrefix(p.URL, "https://orcid.org/"))
+		}
+	}
+
+	text := func(s string) string { return html.EscapeString(strings.TrimSpace(s)) }
+	items := make(map[string][]importedItem)
+	add := func(kind string, item importedItem) {
+		if item.Name != "" {
+			items[kind] = append(items[kind], item)
+		}
+	}
+	for _, w := range res.Work {
+		add("work", importedItem{
+			Name:        joinItemName(text(w.Position), text(w.Name)),
+			Time:        formatItemTime(w.StartDate, w.EndDate),
+			Description: joinText(w.Summary, w.Highlights),
+		})
+	}
+	for _, w := range res.Volunteer {
+		add("volunteer", importedItem{
+			Name:        joinItemName(text(w.Position), text(w.Organization)),
+			Time:        formatItemTime(w.StartDate, w.EndDate),
+			Description: joinText(w.Summary, w.Highlights),
+		})
+	}
+	for _, e := range res.Education {
+		degree := text(e.StudyType)
+		if area := text(e.Area); degree == "" || area == "" {
+			degree += area
+		} else {
+			degree += ", " + area
+		}
+		add("education", importedItem{
+			Name: joinItemName(degree, text(e.Institution)),
+			Time: formatItemTime(e.StartDate, e.EndDate),
+		})
+	}
+	for _, a := range res.Awards {
+		add("awards", importedItem{
+			Name:        joinItemName(text(a.Title), text(a.Awarder)),
+			Time:        formatItemTime(a.Date, a.Date),
+			Description: text(a.Summary),
+		})
+	}
+	for _, p := range res.Projects {
+		name := text(p.Name)
+		if p.URL != "" && name != "" {
+			name = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(p.URL), name)
+		}
+		add("projects", importedItem{
+			Name:        name,
+			Time:        formatItemTime(p.StartDate, p.EndDate),
+			Description: joinText(p.Description, p.Highlights),
+		})
+	}
+	for _, s := range res.Skills {
+		add("skills", importedItem{
+			Name:        joinItemName(text(s.Name), joinText("", s.Keywords)),
+			Description: text(s.Level),
+		})
+	}
+	for _, l := range res.Languages {
+		add("languages", importedItem{Name: text(l.Language), Description: text(l.Fluency)})
+	}
+
+	doc := importedDocument{ID: "cv", Output: filepath.Base(strings.TrimSuffix(dataFile, filepath.Ext(dataFile))) + ".pdf"}
+	bibFile := strings.TrimSuffix(dataFile, filepath.Ext(dataFile)) + ".bib"
+	var bib strings.Builder
+	fmt.Fprintf(&bib, "@comment{Publications imported from %s.\n", filepath.Base(filename))
+	bib.WriteString("JSON Resume does not list the authors of publications, so these\n")
+	bib.WriteString("entries have none. Add them, and change the entry types to more\n")
+	bib.WriteString("specific ones such as article, before relying on the citations.}\n\n")
+	for _, kind := range jsonResumeSections {
+		s := importedSection{ID: kind, Name: jsonResumeNames[kind], JSONResume: kind, Items: items[kind]}
+		if kind == "publications" {
+			// Citation sections are exported as publications anyway.
+			s.JSONResume = ""
+			keys := make(map[string]bool)
+			for _, p := range res.Publications {
+				if strings.TrimSpace(p.Name) == "" {
+					continue
+				}
+				key := bibKey(res.Basics.Name, p, keys)
+				s.Citations = append(s.Citations, key)
+				writeBibEntry(&bib, key, p)
+			}
+			if len(s.Citations) > 0 {
+				d.Bibliographies = []string{filepath.Base(bibFile)}
+			}
+		}
+		if len(s.Items) == 0 && len(s.Citations) == 0 {
+			continue
+		}
+		d.Sections = append(d.Sections, s)
+		doc.Sections = append(doc.Sections, s.ID)
+	}
+	d.Documents = []importedDocument{doc}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "# CV data imported from %s.\n\n", filepath.Base(filename))
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(d); err != nil {
+		return nil, err
+	}
+	// Check both files first, so as not to leave a data file without its
+	// bibliography.
+	for _, f := range []string{dataFile, bibFile} {
+		if _, err := os.Stat(f); err == nil {
+			return nil, fmt.Errorf("%s already exists", f)
+		}
+	}
+	written := []string{dataFile}
+	if err := writeNew(dataFile, out.String()); err != nil {
+		return nil, err
+	}
+	if d.Bibliographies != nil {
+		if err := writeNew(bibFile, bib.String()); err != nil {
+			return written, err
+		}
+		written = append(written, bibFile)
+	}
+	return written, nil
+}
+
+// writeNew writes a file that must not already exist, even if it was
+// created since it was checked.
+func writeNew(filename, contents string) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s already exists", filename)
+	} else if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(contents); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// bibKey returns a new cite key for p, made of the author's family name,
+// the year and the first long word of the title, as in tessum2015inmap.
+func bibKey(author string, p jsonResumePublication, used map[string]bool) string {
+	words := func(s string) []string {
+		return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	}
+	base := ""
+	if names := words(author); len(names) > 0 {
+		base = names[len(names)-1]
+	}
+	year, _, _ := strings.Cut(p.ReleaseDate, "-")
+	base += year
+	for _, w := range words(p.Name) {
+		if len([]rune(w)) > 3 {
+			base += w
+			break
+		}
+	}
+	key := base
+	for i := 'a'; used[key] || key == ""; i++ {
+		key = base + string(i)
+	}
+	used[key] = true
+	return key
+}
+
+// writeBibEntry writes p as a BibTeX misc entry. JSON Resume does not list
+// a publication's authors, so the entry has none.
+func writeBibEntry(b *strings.Builder, key string, p jsonResumePublication) {
+	year, month, _ := strings.Cut(matchItemDate.FindString(p.ReleaseDate), "-")
+	month, _, _ = strings.Cut(month, "-")
+	month = strings.TrimPrefix(month, "0")
+	fmt.Fprintf(b, "@misc{%s,\n", key)
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(b, "  %s = {%s},\n", name, value)
+		}
+	}
+	field("title", escapeLaTeX(p.Name))
+	field("howpublished", escapeLaTeX(p.Publisher))
+	field("year", year)
+	field("month", month)
+	field("url", escapeURL(p.URL))
+	b.WriteString("}\n\n")
+}
+
+// joinText joins text and a list of highlights or keywords, as HTML
+// separated by semicolons.
+func joinText(text string, list []string) string {
+	var parts []string
+	for _, s := range append([]string{text}, list...) {
+		if s = strings.TrimSpace(s); s != "" {
+			parts = append(parts, html.EscapeString(s))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteJSONResume(t *testing.T) {
+	for _, r := range testRenderings(t) {
+		checkGolden(t, r.ID+".json", export(t, r, writeJSONResume))
+	}
+}
+
+// TestImportJSONResume exports a document, imports it as a new data file
+// and exports that again, which must give the same resume apart from the
+// publications' authors, which JSON Resume does not list.
+func TestImportJSONResume(t *testing.T) {
+	r := testRenderings(t)[0]
+	dir := t.TempDir()
+	in := filepath.Join(dir, "resume.json")
+	if err := writeJSONResume(r, in); err != nil {
+		t.Fatal(err)
+	}
+	dataFile := filepath.Join(dir, "Jane_Doe_CV.yaml")
+	written, err := importJSONResume(in, dataFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bibFile := filepath.Join(dir, "Jane_Doe_CV.bib")
+	if want := []string{dataFile, bibFile}; !reflect.DeepEqual(written, want) {
+		t.Errorf("wrote %q, want %q", written, want)
+	}
+	for _, f := range []struct{ file, golden string }{{dataFile, "imported.yaml"}, {bibFile, "imported.bib"}} {
+		b, err := os.ReadFile(f.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, f.golden, b)
+	}
+
+	data, citations, err := load(dataFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := data.rendering(data.Documents[0], citations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := r.jsonResume()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r2.jsonResume()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range []*jsonResume{want, got} {
+		for i := range res.Publications {
+			res.Publications[i].Summary = ""
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip changed the resume:\n got %+v\nwant %+v", got, want)
+	}
+
+	if _, err := importJSONResume(in, dataFile); err == nil {
+		t.Error("importing over an existing data file succeeded")
+	}
+}
+
+func TestSplitItemName(t *testing.T) {
+	for _, tt := range []struct{ in, name, at string }{
+		{"Research Scientist—University of Washington", "Research Scientist", "University of Washington"},
+		{"Go: Python, R", "Go", "Python, R"},
+		{"Go; Python", "Go; Python", ""},
+	} {
+		name, at := splitItemName(tt.in)
+		if name != tt.name || at != tt.at {
+			t.Errorf("splitItemName(%q) = %q, %q; want %q, %q", tt.in, name, at, tt.name, tt.at)
+		}
+		if tt.at != "" {
+			if got, _ := splitItemName(joinItemName(name, at)); got != name {
+				t.Errorf("joinItemName(%q, %q) does not split back", name, at)
+			}
+		}
+	}
+}
+
+func TestParseItemTime(t *testing.T) {
+	for _, tt := range []struct{ in, start, end string }{
+		{"2020–present", "2020", ""},
+		{"2016–2019", "2016", "2019"},
+		{"2014", "2014", "2014"},
+		{"Fall 2020–Present", "2020", ""},
+		{"2019-05–2020-01", "2019-05", "2020-01"},
+		{"", "", ""},
+	} {
+		start, end := parseItemTime(tt.in)
+		if start != tt.start || end != tt.end {
+			t.Errorf("parseItemTime(%q) = %q, %q; want %q, %q", tt.in, start, end, tt.start, tt.end)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// decodeLaTeX converts a BibTeX field value containing LaTeX markup to an
+// HTML fragment: accents and symbols become Unicode characters, text
+// formatting commands become HTML tags, and grouping braces are removed.
+func decodeLaTeX(s string) string {
+	d := &latexDecoder{s: []rune(s)}
+	return strings.TrimSpace(norm.NFC.String(d.text(false)))
+}
+
+// decodeVerbatim decodes a field such as a URL or DOI, where only escaped
+// special characters and braces are interpreted.
+func decodeVerbatim(s string) string {
+	var b strings.Builder
+	r := []rune(strings.Join(strings.Fields(s), ""))
+	for i := 0; i < len(r); i++ {
+		switch {
+		case r[i] == '{' || r[i] == '}':
+		case r[i] == '\\' && i+1 < len(r) && strings.ContainsRune(`_%&#$~{}\`, r[i+1]):
+			i++
+			b.WriteRune(r[i])
+		default:
+			b.WriteRune(r[i])
+		}
+	}
+	return escapeHTML.Replace(b.String())
+}
+
+var escapeHTML = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// accents maps LaTeX accent commands to Unicode combining characters.
+var accents = map[string]rune{
+	"`": '̀', "'": '́', "^": '̂', "~": '̃',
+	"=": '̄', "u": '̆', ".": '̇', `"`: '̈',
+	"r": '̊', "H": '̋', "v": '̌', "d": '̣',
+	"c": '̧', "k": '̨', "b": '̱', "t": '͡',
+}
+
+// symbols maps argument-less LaTeX commands to their text.
+var symbols = map[string]string{
+	"ss": "ß", "o": "ø", "O": "Ø", "ae": "æ", "AE": "Æ", "oe": "œ", "OE": "Œ",
+	"aa": "å", "AA": "Å", "l": "ł", "L": "Ł", "i": "ı", "j": "ȷ",
+	"dh": "ð", "DH": "Ð", "th": "þ", "TH": "Þ", "ng": "ŋ", "NG": "Ŋ",
+
+	"&": "&amp;", "%": "%", "$": "$", "#": "#", "_": "_", "{": "{", "}": "}",
+	" ": " ", ",": " ", ";": " ", ":": " ", "!": "",
+	"-": "", "/": "", "\\": " ", "@": "",
+
+	"textendash": "–", "textemdash": "—", "textbackslash": `\`,
+	"textasciitilde": "~", "textasciicircum": "^", "textunderscore": "_",
+	"textbar": "|", "textless": "&lt;", "textgreater": "&gt;",
+	"textquoteleft": "‘", "textquoteright": "’", "textquotedblleft": "“",
+	"textquotedblright": "”", "S": "§", "P": "¶", "copyright": "©",
+	"textcopyright": "©", "textregistered": "®", "texttrademark": "™",
+	"dag": "†", "ddag": "‡", "textdagger": "†", "textdaggerdbl": "‡",
+	"textdegree": "°", "degree": "°", "ldots": "…", "dots": "…",
+	"textellipsis": "…", "textperthousand": "‰", "euro": "€", "texteuro": "€",
+	"pounds": "£", "textsterling": "£", "textpm": "±", "textmu": "µ",
+	"textbullet": "•", "LaTeX": "LaTeX", "TeX": "TeX", "BibTeX": "BibTeX",
+
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ",
+	"varepsilon": "ε", "zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ",
+	"iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ",
+	"pi": "π", "rho": "ρ", "sigma": "σ", "tau": "τ", "upsilon": "υ",
+	"phi": "ϕ", "varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ",
+	"Pi": "Π", "Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ",
+	"Omega": "Ω",
+
+	"pm": "±", "mp": "∓", "times": "×", "div": "÷", "cdot": "·",
+	"le": "≤", "leq": "≤", "ge": "≥", "geq": "≥", "ne": "≠", "neq": "≠",
+	"approx": "≈", "sim": "∼", "simeq": "≃", "propto": "∝", "infty": "∞",
+	"circ": "∘", "to": "→", "rightarrow": "→", "leftarrow": "←",
+	"leftrightarrow": "↔", "Rightarrow": "⇒", "partial": "∂", "nabla": "∇",
+	"sum": "∑", "prod": "∏", "int": "∫", "ell": "ℓ", "prime": "′",
+	"cdots": "⋯", "in": "∈", "ll": "≪", "gg": "≫", "langle": "⟨",
+	"rangle": "⟩", "quad": " ", "qquad": " ",
+}
+
+// wrappers maps one-argument LaTeX formatting commands, and the equivalent
+// declarations such as {\it ...}, to HTML tags.
+var wrappers = map[string]string{
+	"emph": "i", "textit": "i", "textsl": "i", "mathit": "i", "it": "i",
+	"em": "i", "sl": "i", "textbf": "strong", "mathbf": "strong",
+	"bf": "strong", "textsubscript": "sub", "textsuperscript": "sup",
+	"underline": "u",
+}
+
+// plainCommands have one argument that is output without formatting.
+var plainCommands = map[string]bool{
+	"textrm": true, "textsf": true, "texttt": true, "textnormal": true,
+	"textup": true, "textmd": true, "textsc": true, "mathrm": true,
+	"mathsf": true, "mathtt": true, "mathcal": true, "mathbb": true,
+	"text": true, "mbox": true, "hbox": true, "ensuremath": true,
+	"NoCaseChange": true, "MakeUppercase": true, "MakeLowercase": true,
+	"uppercase": true, "lowercase": true,
+}
+
+// declarations are argument-less commands that only change the font of the
+// rest of the group and have no HTML equivalent.
+var declarations = map[string]bool{
+	"rm": true, "sf": true, "tt": true, "sc": true, "normalfont": true,
+	"upshape": true, "mdseries": true, "scshape": true, "small": true,
+	"footnotesize": true, "large": true, "relax": true, "protect": true,
+}
+
+type latexDecoder struct {
+	s    []rune
+	i    int
+	math bool
+}
+
+func (d *latexDecoder) peek(offset int) rune {
+	if d.i+offset < len(d.s) {
+		return d.s[d.i+offset]
+	}
+	return 0
+}
+
+// text decodes until the end of the input or, if inGroup is true, until
+// the brace that closes the current group, which is consumed.
+func (d *latexDecoder) text(inGroup bool) string {
+	var b strings.Builder
+	for d.i < len(d.s) {
+		c := d.s[d.i]
+		switch {
+		case c == '}':
+			d.i++
+			if inGroup {
+				return b.String()
+			}
+		case c == '{':
+			d.i++
+			b.WriteString(d.text(true))
+		case c == '\\':
+			d.i++
+			name := d.commandName()
+			if tag, ok := wrappers[name]; ok && !d.hasArg() {
+				// A declaration such as {\it ...} applies to the rest of the group.
+				rest := d.text(inGroup)
+				b.WriteString("<" + tag + ">" + strings.TrimSpace(rest) + "</" + tag + ">")
+				return b.String()
+			}
+			b.WriteString(d.command(name))
+		case c == '$':
+			d.i++
+			d.math = !d.math
+		case d.math && (c == '_' || c == '^'):
+			d.i++
+			tag := "sub"
+			if c == '^' {
+				tag = "sup"
+			}
+			arg := d.arg()
+			if tag == "sup" && arg == "∘" {
+				b.WriteString("°")
+			} else {
+				b.WriteString("<" + tag + ">" + arg + "</" + tag + ">")
+			}
+		case c == '~':
+			d.i++
+			b.WriteRune(' ')
+		case c == '-' && !d.math:
+			n := 1
+			for d.peek(n) == '-' && n < 3 {
+				n++
+			}
+			d.i += n
+			b.WriteString([]string{"", "-", "–", "—"}[n])
+		case c == '-':
+			d.i++
+			b.WriteRune('−')
+		case c == '`':
+			if d.peek(1) == '`' {
+				d.i += 2
+				b.WriteRune('“')
+			} else {
+				d.i++
+				b.WriteRune('‘')
+			}
+		case c == '\'' && d.peek(1) == '\'':
+			d.i += 2
+			b.WriteRune('”')
+		case unicode.IsSpace(c):
+			d.skipSpace()
+			b.WriteRune(' ')
+		default:
+			d.i++
+			b.WriteString(escapeHTML.Replace(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// commandName reads the name of the command following a backslash.
+// Outside of math mode, letter commands consume the whitespace that
+// follows them.
+func (d *latexDecoder) commandName() string {
+	start := d.i
+	for d.i < len(d.s) && isLetter(d.s[d.i]) {
+		d.i++
+	}
+	if d.i == start {
+		if d.i < len(d.s) {
+			d.i++
+		}
+		return string(d.s[start:d.i])
+	}
+	name := string(d.s[start:d.i])
+	if !d.math {
+		d.skipSpace()
+	}
+	return name
+}
+
+func isLetter(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func (d *latexDecoder) skipSpace() {
+	for d.i < len(d.s) && unicode.IsSpace(d.s[d.i]) {
+		d.i++
+	}
+}
+
+func (d *latexDecoder) hasArg() bool {
+	return d.peek(0) == '{'
+}
+
+// arg decodes a single command argument: either a braced group or the
+// next character or command.
+func (d *latexDecoder) arg() string {
+	d.skipSpace()
+	if d.i >= len(d.s) {
+		return ""
+	}
+	switch c := d.s[d.i]; c {
+	case '{':
+		d.i++
+		return d.text(true)
+	case '\\':
+		d.i++
+		return d.command(d.commandName())
+	default:
+		d.i++
+		return escapeHTML.Replace(string(c))
+	}
+}
+
+// rawArg returns the undecoded contents of a braced argument.
+func (d *latexDecoder) rawArg() string {
+	d.skipSpace()
+	if d.peek(0) != '{' {
+		return ""
+	}
+	depth := 0
+	start := d.i + 1
+	for ; d.i < len(d.s); d.i++ {
+		switch d.s[d.i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				d.i++
+				return string(d.s[start : d.i-1])
+			}
+		}
+	}
+	return string(d.s[start:])
+}
+
+func (d *latexDecoder) command(name string) string {
+	if mark, ok := accents[name]; ok {
+		return accent(d.arg(), mark)
+	}
+	if s, ok := symbols[name]; ok {
+		return s
+	}
+	if tag, ok := wrappers[name]; ok {
+		return "<" + tag + ">" + d.arg() + "</" + tag + ">"
+	}
+	if plainCommands[name] {
+		return d.arg()
+	}
+	if declarations[name] {
+		return ""
+	}
+	switch name {
+	case "url":
+		return decodeVerbatim(d.rawArg())
+	case "href":
+		u := decodeVerbatim(d.rawArg())
+		return `<a href="` + u + `">` + d.arg() + "</a>"
+	case "sqrt":
+		return "√(" + d.arg() + ")"
+	case "frac":
+		num := d.arg()
+		return num + "/" + d.arg()
+	}
+	// Unknown commands are dropped; any arguments they have are output
+	// as ordinary groups.
+	return ""
+}
+
+// accent adds the combining character mark to the first character of s.
+func accent(s string, mark rune) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return string(mark)
+	}
+	switch r[0] {
+	case 'ı':
+		r[0] = 'i'
+	case 'ȷ':
+		r[0] = 'j'
+	}
+	return norm.NFC.String(string(r[0]) + string(mark) + string(r[1:]))
+}
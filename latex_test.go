@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestDecodeLaTeX(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{`M{\"u}ller and Ram\'{\i}rez`, "Müller and Ramírez"},
+		{`{\c{C}}elik \"Ozt\"urk {\v S}ekli Ma\~{n}ana`, "Çelik Öztürk Šekli Mañana"},
+		{`\ss{} \o{} \AA{} \l{}ódź`, "ß ø Å łódź"},
+		{`PM$_{2.5}$ and NO$_x$`, "PM<sub>2.5</sub> and NO<sub>x</sub>"},
+		{`10$^{\circ}$C \& 10\%`, "10°C &amp; 10%"},
+		{`$\alpha \leq 5 \pm 2$`, "α ≤ 5 ± 2"},
+		{`$-3$ and 1--2 and 1---2`, "−3 and 1–2 and 1—2"},
+		{"``quoted'' x~y", "“quoted” x\u00a0y"},
+		{`\textit{in vivo} {\em emph} \textbf{B}{\bf C}`, "<i>in vivo</i> <i>emph</i> <strong>B</strong><strong>C</strong>"},
+		{`CO\textsubscript{2}`, "CO<sub>2</sub>"},
+		{`{The {GEOS-Chem} Model}`, "The GEOS-Chem Model"},
+		{`\url{http://a.b/c_d} <tag>`, "http://a.b/c_d &lt;tag&gt;"},
+		{"  spaced  ", "spaced"},
+	} {
+		if got := decodeLaTeX(tt.in); got != tt.want {
+			t.Errorf("decodeLaTeX(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeVerbatim(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{" https://x.org/a{\\_}b\\_c\n ", "https://x.org/a_b_c"},
+		{`10.1000/{ABC}\%20`, "10.1000/ABC%20"},
+		{"https://x.org/?a=1&b=2", "https://x.org/?a=1&amp;b=2"},
+	} {
+		if got := decodeVerbatim(tt.in); got != tt.want {
+			t.Errorf("decodeVerbatim(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// linkModes are the texts a document can show for the link of each
+// citation, selected with its links field: the page range where the style
+// links it and otherwise the address (the default), the address, the DOI
+// or arXiv identifier, or the word "link".
+var linkModes = []string{"pages", "url", "doi", "link"}
+
+var (
+	doiPattern   = regexp.MustCompile(`^10\.\d{4,9}/\S+$`)
+	arXivPattern = regexp.MustCompile(`^(\d{4}\.\d{4,5}|[a-z-]+(\.[A-Z]{2})?/\d{7})(v\d+)?$`)
+)
+
+// normalizeDOI removes resolver and "doi:" prefixes from a DOI and checks
+// its syntax.
+func normalizeDOI(s string) (string, error) {
+	doi := strings.TrimSpace(s)
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://www.doi.org/", "http://www.doi.org/",
+		"https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		if len(doi) >= len(prefix) && strings.EqualFold(doi[:len(prefix)], prefix) {
+			doi = strings.TrimSpace(doi[len(prefix):])
+		}
+	}
+	if !doiPattern.MatchString(doi) {
+		return "", fmt.Errorf("invalid DOI %s", s)
+	}
+	return doi, nil
+}
+
+// normalizeArXiv removes the "arXiv:", abstract page and PDF prefixes from
+// an arXiv identifier, such as "2410.24116" or "hep-th/9901001", and checks
+// its syntax.
+func normalizeArXiv(s string) (string, error) {
+	id := strings.TrimSpace(s)
+	for _, prefix := range []string{"https://arxiv.org/abs/", "http://arxiv.org/abs/", "https://www.arxiv.org/abs/",
+		"https://arxiv.org/pdf/", "http://arxiv.org/pdf/", "https://www.arxiv.org/pdf/", "arXiv:"} {
+		if len(id) >= len(prefix) && strings.EqualFold(id[:len(prefix)], prefix) {
+			id = strings.TrimSuffix(strings.TrimSpace(id[len(prefix):]), ".pdf")
+		}
+	}
+	if !arXivPattern.MatchString(id) {
+		return "", fmt.Errorf("invalid arXiv identifier %s", s)
+	}
+	return id, nil
+}
+
+// doi returns the normalized DOI of e, or "" if it has none or it is
+// invalid.
+func (e *bibEntry) doi() string {
+	if !e.has("doi") {
+		return ""
+	}
+	doi, _ := normalizeDOI(e.field("doi"))
+	return doi
+}
+
+// isArXiv reports whether the eprint field of e is an arXiv identifier.
+func (e *bibEntry) isArXiv() bool {
+	return e.has("eprint") && (strings.EqualFold(e.field("archiveprefix"), "arxiv") ||
+		strings.EqualFold(e.field("eprinttype"), "arxiv"))
+}
+
+// arXiv returns the normalized arXiv identifier of e, or "" if it has none
+// or it is invalid. Without an arXiv eprint field, the identifier is taken
+// from an arxiv.org url or from pages such as "arXiv:2211.03906".
+func (e *bibEntry) arXiv() string {
+	if e.isArXiv() {
+		id, _ := normalizeArXiv(e.field("eprint"))
+		return id
+	}
+	if u := e.field("url"); strings.Contains(strings.ToLower(u), "arxiv.org/") {
+		if id, err := normalizeArXiv(u); err == nil {
+			return id
+		}
+	}
+	if p := strings.TrimSpace(e.field("pages")); len(p) > len("arXiv:") && strings.EqualFold(p[:len("arXiv:")], "arXiv:") {
+		if id, err := normalizeArXiv(p); err == nil {
+			return id
+		}
+	}
+	return ""
+}
+
+// checkLinks reports an invalid DOI or arXiv identifier in e.
+func (e *bibEntry) checkLinks() error {
+	if e.has("doi") {
+		if _, err := normalizeDOI(e.field("doi")); err != nil {
+			return err
+		}
+	}
+	if e.isArXiv() {
+		if _, err := normalizeArXiv(e.field("eprint")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkURL returns the address that a citation of e links to: its DOI,
+// its url field, or its arXiv abstract page, in that order.
+func (e *bibEntry) linkURL() string {
+	if doi := e.doi(); doi != "" {
+		return "https://doi.org/" + doi
+	}
+	if u := strings.TrimSpace(e.field("url")); u != "" {
+		return u
+	}
+	if id := e.arXiv(); id != "" {
+		return "https://arxiv.org/abs/" + id
+	}
+	return ""
+}
+
+// link returns the link of e as HTML, showing the text given by the link
+// mode of e. pages is the page range, if the style links it. If e has no
+// address, link returns pages.
+func (e *bibEntry) link(pages string) string {
+	u := e.linkURL()
+	if u == "" {
+		return pages
+	}
+	return fmt.Sprintf("<a href=%s>%s</a>", u, e.linkText(u, pages))
+}
+
+// linkText returns the text shown for a link of e to the address u,
+// according to the link mode of e. pages is the page range, if the style
+// links it.
+func (e *bibEntry) linkText(u, pages string) string {
+	switch e.links {
+	case "", "pages":
+		if pages != "" {
+			return pages
+		}
+	case "doi":
+		if doi := e.doi(); doi != "" {
+			return "doi:" + doi
+		} else if id := e.arXiv(); id != "" {
+			return "arXiv:" + id
+		}
+	case "link":
+		return "link"
+	}
+	return u
+}
+
+// withLinks returns a copy of e whose link is shown according to mode.
+func (e *bibEntry) withLinks(mode string) *bibEntry {
+	c := *e
+	c.links = mode
+	return &c
+}
+
+// linkStyle shows the links of another style according to a link mode.
+type linkStyle struct {
+	Style
+	mode string
+}
+
+func (s linkStyle) Format(e *bibEntry) (string, error) {
+	return s.Style.Format(e.withLinks(s.mode))
+}
+
+func checkLinkMode(mode string) error {
+	if mode == "" || slices.Contains(linkModes, mode) {
+		return nil
+	}
+	return fmt.Errorf("unknown links mode '%s' (available: %s)", mode, strings.Join(linkModes, ", "))
+}
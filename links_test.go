@@ -0,0 +1,92 @@
+package main
+
+import (
+	"html/template"
+	"testing"
+)
+
+func TestNormalizeDOI(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"10.1073/pnas.1406853111", "10.1073/pnas.1406853111"},
+		{" doi:10.1000/xyz ", "10.1000/xyz"},
+		{"https://doi.org/10.1029/2024JH000132", "10.1029/2024JH000132"},
+		{"https://www.doi.org/10.1029/2024JH000132", "10.1029/2024JH000132"},
+		{"http://DX.doi.org/10.1000/a", "10.1000/a"},
+		{"10.100/x", ""},
+		{"https://dx.doi.org/10.1000/a b", ""},
+		{"pnas.1406853111", ""},
+	} {
+		got, err := normalizeDOI(tt.in)
+		if got != tt.want || (err != nil) != (tt.want == "") {
+			t.Errorf("normalizeDOI(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeArXiv(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"2410.24116", "2410.24116"},
+		{"arXiv:2410.24116v2", "2410.24116v2"},
+		{"hep-th/9901001", "hep-th/9901001"},
+		{"math.GT/0309136", "math.GT/0309136"},
+		{"https://arxiv.org/abs/2211.03906", "2211.03906"},
+		{"https://www.arxiv.org/pdf/2211.03906v1.pdf", "2211.03906v1"},
+		{"https://arxiv.org/abs/1234.5", ""},
+		{"arXiv preprint", ""},
+	} {
+		got, err := normalizeArXiv(tt.in)
+		if got != tt.want || (err != nil) != (tt.want == "") {
+			t.Errorf("normalizeArXiv(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestLinks(t *testing.T) {
+	citations := testBib(t, `
+@article{doi, title = {T}, doi = {https://doi.org/10.1000/xyz}, url = {https://example.com/a}}
+@article{url, title = {T}, url = {https://example.com/a}}
+@article{eprint, title = {T}, eprint = {2211.03906}, archiveprefix = {arXiv}}
+@article{arxivurl, title = {T}, url = {https://arxiv.org/abs/2211.03906}}
+@article{arxivpages, title = {T}, pages = {arXiv:2211.03906}}
+@article{none, title = {T}, pages = {1--2}}
+`)
+	for _, tt := range []struct {
+		key   template.HTML
+		pages string
+		want  map[string]string // by link mode
+	}{
+		{"doi", "", map[string]string{
+			"":    "<a href=https://doi.org/10.1000/xyz>https://doi.org/10.1000/xyz</a>",
+			"doi": "<a href=https://doi.org/10.1000/xyz>doi:10.1000/xyz</a>",
+		}},
+		{"doi", "1–2", map[string]string{
+			"pages": "<a href=https://doi.org/10.1000/xyz>1–2</a>",
+			"url":   "<a href=https://doi.org/10.1000/xyz>https://doi.org/10.1000/xyz</a>",
+			"link":  "<a href=https://doi.org/10.1000/xyz>link</a>",
+		}},
+		{"url", "", map[string]string{
+			"":    "<a href=https://example.com/a>https://example.com/a</a>",
+			"doi": "<a href=https://example.com/a>https://example.com/a</a>",
+		}},
+		{"eprint", "", map[string]string{
+			"url": "<a href=https://arxiv.org/abs/2211.03906>https://arxiv.org/abs/2211.03906</a>",
+			"doi": "<a href=https://arxiv.org/abs/2211.03906>arXiv:2211.03906</a>",
+		}},
+		{"arxivurl", "", map[string]string{
+			"doi": "<a href=https://arxiv.org/abs/2211.03906>arXiv:2211.03906</a>",
+		}},
+		{"arxivpages", "", map[string]string{
+			"doi": "<a href=https://arxiv.org/abs/2211.03906>arXiv:2211.03906</a>",
+		}},
+		{"none", "1–2", map[string]string{
+			"":    "1–2",
+			"doi": "1–2",
+		}},
+	} {
+		for mode, want := range tt.want {
+			if got := citations[tt.key].withLinks(mode).link(tt.pages); got != want {
+				t.Errorf("%s, mode %q: got %q, want %q", tt.key, mode, got, want)
+			}
+		}
+	}
+}
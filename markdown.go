@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// writeMarkdown writes r as a Markdown document. Reversed citation
+// numbers are written out, since Markdown lists can only count up.
+func writeMarkdown(r *rendering, filename string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", markdownEscaper.Replace(r.Owner.Name))
+	var lines []string
+	for _, l := range r.Owner.contactLines() {
+		lines = append(lines, markdownText(parseFragment(l)))
+	}
+	if len(lines) > 0 {
+		// A trailing backslash is a line break.
+		fmt.Fprintf(&b, "%s\n\n", strings.Join(lines, "\\\n"))
+	}
+	b.WriteString("---\n")
+	for _, s := range r.Sections {
+		heading := markdownText(parseFragment(string(s.Name) + string(r.legend(s.Citations, s.Authors))))
+		fmt.Fprintf(&b, "\n## %s\n", heading)
+		var groups []citationGroup
+		if len(s.Citations) > 0 {
+			groups = r.groups(s)
+		}
+		for _, g := range groups {
+			if s.GroupByYear {
+				fmt.Fprintf(&b, "\n### %s\n", markdownEscaper.Replace(g.Year))
+			}
+			for i, key := range g.Citations {
+				text, err := r.ref(key, s.Authors)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(&b, "\n%d\\. %s\n", g.Start-i, markdownText(parseFragment(string(text))))
+			}
+		}
+		if len(s.Items) > 0 {
+			b.WriteString("\n")
+		}
+		for _, item := range s.Items {
+			fmt.Fprintf(&b, "- %s", markdownText(parseFragment(string(item.Name))))
+			if item.Time != "" {
+				fmt.Fprintf(&b, " (%s)", markdownText(parseFragment(string(item.Time))))
+			}
+			if item.Description != "" {
+				fmt.Fprintf(&b, "\\\n  %s", markdownText(parseFragment(string(item.Description))))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return os.WriteFile(filename, []byte(b.String()), 0644)
+}
+
+// markdownEscaper escapes the characters of text that Markdown would take
+// as markup.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`,
+)
+
+// markdownText converts runs to Markdown. Underlining, superscripts and
+// subscripts, which Markdown lacks, are written as HTML; small text is
+// shown as normal text.
+func markdownText(runs []textRun) string {
+	var b strings.Builder
+	for _, run := range runs {
+		if run.Break {
+			b.WriteString("\\\n")
+			continue
+		}
+		s := markdownEscaper.Replace(run.Text)
+		for _, f := range []struct {
+			on          bool
+			left, right string
+		}{
+			{run.Italic, "*", "*"},
+			{run.Bold, "**", "**"},
+			{run.Underline, "<ins>", "</ins>"},
+			{run.Sup, "<sup>", "</sup>"},
+			{run.Sub, "<sub>", "</sub>"},
+		} {
+			if f.on {
+				s = markdownWrap(s, f.left, f.right)
+			}
+		}
+		if run.Href != "" {
+			s = markdownWrap(s, "[", "]("+strings.ReplaceAll(run.Href, ")", "%29")+")")
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+// markdownWrap encloses s in left and right, leaving out leading and
+// trailing spaces, which Markdown does not allow inside emphasis.
+func markdownWrap(s, left, right string) string {
+	text := strings.TrimFunc(s, unicode.IsSpace)
+	if text == "" {
+		return s
+	}
+	i := strings.Index(s, text)
+	return s[:i] + left + text + right + s[i+len(text):]
+}
@@ -0,0 +1,9 @@
+package main
+
+import "testing"
+
+func TestWriteMarkdown(t *testing.T) {
+	for _, r := range testRenderings(t) {
+		checkGolden(t, r.ID+".md", export(t, r, writeMarkdown))
+	}
+}
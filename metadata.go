@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// title returns the title of the document: its title field, or the
+// owner's name followed by "CV".
+func (r *rendering) title() string {
+	return cmp.Or(r.Title, strings.TrimSpace(r.Owner.Name+" CV"))
+}
+
+// pdfInfo returns the entries of the PDF document information dictionary
+// for r, leaving out empty ones.
+func (r *rendering) pdfInfo() [][2]string {
+	var info [][2]string
+	for _, e := range [][2]string{
+		{"Title", r.title()},
+		{"Author", r.Owner.Name},
+		{"Subject", r.Subject},
+		{"Keywords", strings.Join(r.Keywords, ", ")},
+	} {
+		if e[1] != "" {
+			info = append(info, e)
+		}
+	}
+	return info
+}
+
+// setPDFInfo sets the document information of the PDF file filename,
+// which must use a cross-reference table as Chrome writes, by appending
+// an incremental update.
+func setPDFInfo(filename string, info [][2]string) error {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	b, err = appendPDFInfo(b, info)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	return os.WriteFile(filename, b, 0644)
+}
+
+var (
+	pdfSizePattern = regexp.MustCompile(`/Size\s+(\d+)`)
+	pdfRootPattern = regexp.MustCompile(`/Root\s+\d+\s+\d+\s+R`)
+	pdfIDPattern   = regexp.MustCompile(`/ID\s*\[[^\]]*\]`)
+)
+
+// appendPDFInfo returns the PDF b with an incremental update that adds a
+// new document information dictionary with the given entries.
+func appendPDFInfo(b []byte, info [][2]string) ([]byte, error) {
+	i := bytes.LastIndex(b, []byte("startxref"))
+	j := bytes.LastIndex(b[:max(i, 0)], []byte("trailer"))
+	if i < 0 || j < 0 {
+		return nil, fmt.Errorf("adding metadata: no cross-reference table found")
+	}
+	trailer := b[j:i]
+	fields := bytes.Fields(b[i+len("startxref"):])
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("adding metadata: invalid startxref")
+	}
+	prev, err := strconv.Atoi(string(fields[0]))
+	if err != nil {
+		return nil, fmt.Errorf("adding metadata: invalid startxref")
+	}
+	size := pdfSizePattern.FindSubmatch(trailer)
+	root := pdfRootPattern.Find(trailer)
+	if size == nil || root == nil {
+		return nil, fmt.Errorf("adding metadata: invalid trailer")
+	}
+	obj, _ := strconv.Atoi(string(size[1]))
+
+	out := bytes.NewBuffer(b)
+	if !bytes.HasSuffix(b, []byte("\n")) {
+		out.WriteByte('\n')
+	}
+	offset := out.Len()
+	fmt.Fprintf(out, "%d 0 obj\n<<", obj)
+	for _, e := range info {
+		fmt.Fprintf(out, " /%s %s", e[0], pdfTextString(e[1]))
+	}
+	out.WriteString(" >>\nendobj\n")
+	xref := out.Len()
+	fmt.Fprintf(out, "xref\n%d 1\n%010d 00000 n \ntrailer\n<< /Size %d %s /Info %d 0 R /Prev %d", obj, offset, obj+1, root, obj, prev)
+	if id := pdfIDPattern.Find(trailer); id != nil {
+		fmt.Fprintf(out, " %s", id)
+	}
+	fmt.Fprintf(out, " >>\nstartxref\n%d\n%%%%EOF\n", xref)
+	return out.Bytes(), nil
+}
+
+// pdfTextString encodes s as a PDF text string in UTF-16.
+func pdfTextString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, c := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", c)
+	}
+	b.WriteString(">")
+	return b.String()
+}
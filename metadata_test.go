@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-pdf/fpdf"
+)
+
+func TestPDFTextString(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"", "<FEFF>"},
+		{"CV", "<FEFF00430056>"},
+		{"Müller", "<FEFF004D00FC006C006C00650072>"},
+		{"𝐀", "<FEFFD835DC00>"},
+	} {
+		if got := pdfTextString(tt.in); got != tt.want {
+			t.Errorf("pdfTextString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAppendPDFInfo(t *testing.T) {
+	pdf := fpdf.New("P", "pt", "Letter", "")
+	pdf.AddPage()
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	orig := buf.Bytes()
+	prev := lastStartXref(t, orig)
+
+	b, err := appendPDFInfo(bytes.Clone(orig), [][2]string{{"Title", "CV"}, {"Author", "Jane Doe"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(b, orig) {
+		t.Fatal("the original PDF was changed")
+	}
+	update := string(b[len(orig):])
+	m := regexp.MustCompile(`xref\n(\d+) 1\n(\d{10}) 00000 n \ntrailer\n<< /Size (\d+) /Root \d+ 0 R /Info (\d+) 0 R /Prev (\d+)`).FindStringSubmatch(update)
+	if m == nil {
+		t.Fatalf("no cross-reference section for the update:\n%s", update)
+	}
+	obj, offset, size, info, p := m[1], atoi(t, m[2]), atoi(t, m[3]), m[4], atoi(t, m[5])
+	if info != obj || size != atoi(t, obj)+1 || p != prev {
+		t.Errorf("trailer refers to object %s, size %d and previous %d; want object %s, size %d and previous %d", info, size, p, obj, atoi(t, obj)+1, prev)
+	}
+	want := fmt.Sprintf("%s 0 obj\n<< /Title %s /Author %s >>\nendobj\n", obj, pdfTextString("CV"), pdfTextString("Jane Doe"))
+	if !strings.HasPrefix(string(b[offset:]), want) {
+		t.Errorf("object at offset %d is %.60q, want %q", offset, b[offset:], want)
+	}
+	if x := lastStartXref(t, b); !bytes.HasPrefix(b[x:], []byte("xref\n"+obj+" 1\n")) {
+		t.Errorf("startxref %d does not point to the new cross-reference section", x)
+	}
+	if !strings.HasSuffix(update, "%%EOF\n") {
+		t.Error("the update does not end with an end-of-file marker")
+	}
+
+	for _, bad := range []string{"not a PDF", "%PDF-1.4\ntrailer\n<< /Root 1 0 R >>\nstartxref\n9\n%%EOF", "%PDF-1.4\ntrailer\n<< /Size 2 /Root 1 0 R >>\nstartxref\nx\n%%EOF"} {
+		if _, err := appendPDFInfo([]byte(bad), nil); err == nil {
+			t.Errorf("appendPDFInfo(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+// lastStartXref returns the offset given by the last startxref in b.
+func lastStartXref(t *testing.T, b []byte) int {
+	t.Helper()
+	i := bytes.LastIndex(b, []byte("startxref"))
+	if i < 0 {
+		t.Fatal("no startxref")
+	}
+	return atoi(t, string(bytes.Fields(b[i+len("startxref"):])[0]))
+}
+
+func atoi(t *testing.T, s string) int {
+	t.Helper()
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
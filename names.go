@@ -0,0 +1,287 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A name is a personal or corporate name split into the four parts
+// defined by BibTeX, e.g. "van der Berg, Jr., Jan Willem" has First
+// "Jan Willem", Von "van der", Last "Berg" and Jr "Jr.". The parts are
+// decoded from LaTeX.
+type name struct {
+	First, Von, Last, Jr string
+
+	// Initials are the abbreviated given names, e.g. "J.W." for
+	// "Jan Willem" and "J.-P." for "Jean-Paul". A braced group such as
+	// "{Qurat ul ain}" is a single name.
+	Initials string
+
+	// Corporate is true for names that were entirely enclosed in braces,
+	// such as "{World Health Organization}", which are never abbreviated.
+	Corporate bool
+
+	// Corresponding is true if the name was marked with an asterisk.
+	Corresponding bool
+
+	// Role is the role of the person with this name in the data file's
+	// list of people, or nil.
+	Role *Role
+
+	// Markers are added after the name, such as the corresponding author
+	// marker.
+	Markers []*Marker
+}
+
+// family returns the von and last parts of the name, e.g. "van der Berg".
+func (n name) family() string {
+	return strings.TrimSpace(n.Von + " " + n.Last)
+}
+
+// initials abbreviates the given names in the undecoded words.
+func initials(words []string) string {
+	var b strings.Builder
+	for _, word := range words {
+		if strings.HasPrefix(word, "{") && !strings.HasPrefix(word, `{\`) {
+			word = decodeLaTeX(word)
+			word, _, _ = strings.Cut(word, " ")
+		} else {
+			word = decodeLaTeX(word)
+		}
+		for i, part := range strings.Split(stripTags(word), "-") {
+			if i > 0 {
+				b.WriteString("-")
+			}
+			// Given names that are already abbreviated, such as "C.W.",
+			// contribute one initial per letter.
+			for _, p := range strings.Split(part, ".") {
+				r, _ := utf8.DecodeRuneInString(p)
+				if r == utf8.RuneError {
+					continue
+				}
+				b.WriteRune(unicode.ToUpper(r))
+				b.WriteString(".")
+			}
+		}
+	}
+	return b.String()
+}
+
+// isOthers reports whether n is the BibTeX "others" placeholder that
+// stands for omitted authors.
+func (n name) isOthers() bool {
+	return n.First == "" && n.Von == "" && n.Jr == "" && n.Last == "others"
+}
+
+// trimOthers removes a trailing "others" placeholder from a list of two or
+// more names and reports whether there was one.
+func trimOthers(names []name) ([]name, bool) {
+	if len(names) > 1 && names[len(names)-1].isOthers() {
+		return names[:len(names)-1], true
+	}
+	return names, false
+}
+
+// parseNames parses a BibTeX name list such as the author or editor field.
+func parseNames(s string) []name {
+	var out []name
+	for _, a := range splitNames(s) {
+		out = append(out, parseName(a))
+	}
+	return out
+}
+
+// splitNames splits a name list on the word "and" outside of braces.
+func splitNames(s string) []string {
+	words := splitWords(s, false)
+	var out []string
+	var cur []string
+	for _, w := range words {
+		if strings.EqualFold(w, "and") {
+			if len(cur) > 0 {
+				out = append(out, strings.Join(cur, " "))
+			}
+			cur = nil
+			continue
+		}
+		cur = append(cur, w)
+	}
+	if len(cur) > 0 {
+		out = append(out, strings.Join(cur, " "))
+	}
+	return out
+}
+
+// parseName parses a single BibTeX name in any of the forms
+// "First von Last", "von Last, First" or "von Last, Jr, First".
+func parseName(s string) name {
+	var n name
+	if strings.Contains(s, "*") {
+		n.Corresponding = true
+		s = strings.Replace(s, "*", "", -1)
+	}
+	s = strings.TrimSpace(s)
+	if len(splitWords(s, false)) == 1 && strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") &&
+		!strings.HasPrefix(s, `{\`) {
+		n.Last = decodeLaTeX(s)
+		n.Corporate = true
+		return n
+	}
+
+	var parts [][]string
+	for _, p := range splitCommas(s) {
+		parts = append(parts, splitWords(p, true))
+	}
+	// Tolerate stray trailing commas, as in "Tessum, Christopher W.,".
+	for len(parts) > 1 && len(parts[len(parts)-1]) == 0 {
+		parts = parts[:len(parts)-1]
+	}
+	var first, vonLast, jr []string
+	switch len(parts) {
+	case 1:
+		words := parts[0]
+		if len(words) == 0 {
+			return name{}
+		}
+		// The von part runs from the first to the last lower-case word,
+		// but the last word always belongs to the last name.
+		start := -1
+		for i, w := range words[:len(words)-1] {
+			if isLowerWord(w) {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			first = words[:len(words)-1]
+			vonLast = words[len(words)-1:]
+		} else {
+			first = words[:start]
+			vonLast = words[start:]
+		}
+	case 2:
+		vonLast, first = parts[0], parts[1]
+	default:
+		vonLast, jr, first = parts[0], parts[1], strings.Fields(strings.Join(flatten(parts[2:]), " "))
+	}
+
+	// In "von Last", the von part ends with the last lower-case word that
+	// leaves at least one word for the last name.
+	v := 0
+	for i, w := range vonLast[:max(len(vonLast)-1, 0)] {
+		if isLowerWord(w) {
+			v = i + 1
+		}
+	}
+	n.First = decodeLaTeX(strings.Join(first, " "))
+	n.Initials = initials(first)
+	n.Von = decodeLaTeX(strings.Join(vonLast[:v], " "))
+	n.Last = decodeLaTeX(strings.Join(vonLast[v:], " "))
+	n.Jr = decodeLaTeX(strings.Join(jr, " "))
+	return n
+}
+
+func flatten(parts [][]string) []string {
+	var out []string
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// splitWords splits s on whitespace outside of braces. If tilde is true,
+// ties (~) also separate words.
+func splitWords(s string, tilde bool) []string {
+	var out []string
+	depth := 0
+	start := -1
+	for i, c := range s {
+		sep := depth == 0 && (unicode.IsSpace(c) || tilde && c == '~')
+		switch {
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		}
+		if sep {
+			if start >= 0 {
+				out = append(out, s[start:i])
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		out = append(out, s[start:])
+	}
+	return out
+}
+
+// splitCommas splits s on commas outside of braces.
+func splitCommas(s string) []string {
+	var out []string
+	depth := 0
+	start := 0
+	for i, c := range s {
+		switch {
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case c == ',' && depth == 0:
+			out = append(out, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(out, strings.TrimSpace(s[start:]))
+}
+
+// isLowerWord reports whether a name word is part of a von particle. As
+// in BibTeX, this is decided by the first letter outside of braces, except
+// that a brace group starting with a command such as {\"u} counts as a
+// letter.
+func isLowerWord(w string) bool {
+	depth := 0
+	for i, c := range w {
+		switch {
+		case c == '{':
+			if depth == 0 && strings.HasPrefix(w[i:], `{\`) {
+				return unicode.IsLower(firstLetter(decodeLaTeX(w[i:])))
+			}
+			depth++
+		case c == '}':
+			depth--
+		case depth == 0 && unicode.IsLetter(c):
+			return unicode.IsLower(c)
+		}
+	}
+	return false
+}
+
+func firstLetter(s string) rune {
+	for _, c := range s {
+		if unicode.IsLetter(c) {
+			return c
+		}
+	}
+	return 0
+}
+
+// stripTags removes HTML tags from s.
+func stripTags(s string) string {
+	var b strings.Builder
+	inTag := false
+	for _, c := range s {
+		switch {
+		case c == '<':
+			inTag = true
+		case c == '>':
+			inTag = false
+		case !inTag:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseName(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want name
+	}{
+		{"Christopher W. Tessum", name{First: "Christopher W.", Last: "Tessum", Initials: "C.W."}},
+		{"Tessum, Christopher W", name{First: "Christopher W", Last: "Tessum", Initials: "C.W."}},
+		{"van der Berg, Jan Willem", name{First: "Jan Willem", Von: "van der", Last: "Berg", Initials: "J.W."}},
+		{"Smith, Jr., John", name{First: "John", Last: "Smith", Jr: "Jr.", Initials: "J."}},
+		{"Ludwig van Beethoven", name{First: "Ludwig", Von: "van", Last: "Beethoven", Initials: "L."}},
+		{"Jean-Paul Sartre", name{First: "Jean-Paul", Last: "Sartre", Initials: "J.-P."}},
+		{`{\"O}zt{\"u}rk, Ali`, name{First: "Ali", Last: "Öztürk", Initials: "A."}},
+		{"Tessum, C.W.", name{First: "C.W.", Last: "Tessum", Initials: "C.W."}},
+		{"{Qurat ul ain} Khan", name{First: "Qurat ul ain", Last: "Khan", Initials: "Q."}},
+		{`{\relax Ch}ristopher Smith`, name{First: "Christopher", Last: "Smith", Initials: "C."}},
+		{"{World Health Organization}", name{Last: "World Health Organization", Corporate: true}},
+		{"Plato", name{Last: "Plato"}},
+		{"Tessum, Christopher W*", name{First: "Christopher W", Last: "Tessum", Initials: "C.W.", Corresponding: true}},
+		{"", name{}},
+		{"  \t", name{}},
+		{",", name{}},
+		{"*", name{}},
+		{" , ,", name{}},
+	} {
+		got := parseName(tt.in)
+		if got.First != tt.want.First || got.Von != tt.want.Von || got.Last != tt.want.Last || got.Jr != tt.want.Jr ||
+			got.Initials != tt.want.Initials || got.Corporate != tt.want.Corporate || got.Corresponding != tt.want.Corresponding {
+			t.Errorf("parseName(%q) =\n %+v, want\n %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseNames(t *testing.T) {
+	for _, tt := range []struct {
+		in     string
+		want   []string
+		others bool
+	}{
+		{"Tessum, Christopher and Hill, Jason", []string{"Tessum", "Hill"}, false},
+		{"A. Smith AND B. Jones and others", []string{"Smith", "Jones"}, true},
+		{"{Tessum and Sons} and Jones, B.", []string{"Tessum and Sons", "Jones"}, false},
+		{"Smith and and Jones", []string{"Smith", "Jones"}, false},
+		{"others", []string{"others"}, false},
+	} {
+		names, others := trimOthers(parseNames(tt.in))
+		var got []string
+		for _, n := range names {
+			got = append(got, n.Last)
+		}
+		if !slices.Equal(got, tt.want) || others != tt.others {
+			t.Errorf("parseNames(%q) = %q, %v; want %q, %v", tt.in, got, others, tt.want, tt.others)
+		}
+	}
+}
+
+func TestBlankNames(t *testing.T) {
+	_, _, err := testData(t, `
+owner: {name: Jane Doe}
+bibliographies: [test.bib]
+roles: [{id: self}]
+people:
+  - {name: ' ', role: self}
+  - {name: '*', role: self}
+  - {name: 'Doe, Jane', aliases: [' ', ','], role: self}
+sections:
+  - {id: pubs, name: Publications, citations: [doe]}
+documents:
+  - {id: cv, output: cv.pdf, sections: [pubs]}
+`, `@article{doe, author = {Doe, Jane}, title = {T}, journal = {J}, year = {2020},
+  corresponding = {*}, equalcontrib = {Doe, Jane and ,}}`)
+	if err == nil {
+		t.Fatal("blank names were accepted")
+	}
+	for _, want := range []string{
+		"person 0: missing name",
+		`person 1: blank name "*"`,
+		`person Doe, Jane: blank alias " "`,
+		`person Doe, Jane: blank alias ","`,
+		"doe: blank corresponding name",
+		"doe: blank equalcontrib name",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing diagnostic %q in:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pageFields are the placeholders that can be used in page headers and
+// footers: the owner's name, the date of the build, the page number and
+// the number of pages.
+var pageFields = []string{"name", "date", "page", "pages"}
+
+var pageFieldPattern = regexp.MustCompile(`\{([A-Za-z]+)\}`)
+
+// pageDateLayout is the format of the {date} placeholder.
+const pageDateLayout = "January 2, 2006"
+
+// PageText is plain text shown at the top or bottom of every page of a
+// PDF, aligned left, centered and right. In the data file a bare string is
+// short for the centered text.
+type PageText struct {
+	Left   string `yaml:"left"`
+	Center string `yaml:"center"`
+	Right  string `yaml:"right"`
+
+	line int
+}
+
+func (p *PageText) UnmarshalYAML(n *yaml.Node) error {
+	p.line = n.Line
+	if n.Kind == yaml.ScalarNode {
+		return n.Decode(&p.Center)
+	}
+	type plain PageText
+	return decodeStrict(n, (*plain)(p), "page text")
+}
+
+// parts returns the left, center and right text of p.
+func (p *PageText) parts() []string {
+	return []string{p.Left, p.Center, p.Right}
+}
+
+func (p *PageText) validate() error {
+	for _, s := range p.parts() {
+		for _, m := range pageFieldPattern.FindAllStringSubmatch(s, -1) {
+			if !slices.Contains(pageFields, m[1]) {
+				return fmt.Errorf("unknown placeholder %s (available: {%s})", m[0], strings.Join(pageFields, "}, {"))
+			}
+		}
+	}
+	return nil
+}
+
+// pageValues returns the values of the page fields for a page, given
+// as text or markup as the output format requires.
+func (r *rendering) pageValues(page, pages string, escape func(string) string) map[string]string {
+	return map[string]string{
+		"name":  escape(r.Owner.Name),
+		"date":  escape(r.Date.Format(pageDateLayout)),
+		"page":  page,
+		"pages": pages,
+	}
+}
+
+// expandPage replaces the placeholders in s with values, escaping the
+// rest of s with escape.
+func expandPage(s string, values map[string]string, escape func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range pageFieldPattern.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(escape(s[last:m[0]]))
+		if v, ok := values[s[m[2]:m[3]]]; ok {
+			b.WriteString(v)
+		} else {
+			b.WriteString(escape(s[m[0]:m[1]]))
+		}
+		last = m[1]
+	}
+	b.WriteString(escape(s[last:]))
+	return b.String()
+}
+
+// chromePageTemplate returns p as a header or footer template for Chrome's
+// print to PDF, which fills in the page numbers, or "" if p is nil.
+// Chrome does not apply the page's styles to the template, so the font is
+// set here.
+func (r *rendering) chromePageTemplate(p *PageText) string {
+	if p == nil {
+		return ""
+	}
+	values := r.pageValues(`<span class="pageNumber"></span>`, `<span class="totalPages"></span>`, html.EscapeString)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<div style="font-family:'Times New Roman',serif;font-size:9pt;width:100%%;margin:0 %gin 0 %gin;display:flex">`,
+		r.Print.Margins.Right, r.Print.Margins.Left)
+	for i, s := range p.parts() {
+		align := []string{"left", "center", "right"}[i]
+		s = expandPage(s, values, html.EscapeString)
+		fmt.Fprintf(&b, `<span style="flex:1;text-align:%s">%s</span>`, align, s)
+	}
+	b.WriteString("</div>")
+	return b.String()
+}
+
+// paperSizes are the paper sizes that documents can be printed on, as
+// width and height in points.
+var paperSizes = map[string][2]float64{
+	"letter":  {612, 792},
+	"legal":   {612, 1008},
+	"tabloid": {792, 1224},
+	"a3":      {841.89, 1190.55},
+	"a4":      {595.28, 841.89},
+	"a5":      {419.53, 595.28},
+}
+
+// PrintSettings are the page setup of a document's PDF.
+type PrintSettings struct {
+	Paper     string  `yaml:"paper"`   // one of paperSizes
+	Margins   Margins `yaml:"margins"` // in inches
+	Scale     float64 `yaml:"scale"`   // of the content, from 0.1 to 2
+	Landscape bool    `yaml:"landscape"`
+
+	line int
+}
+
+// defaultPrintSettings are the print settings of a document that does not
+// set them.
+var defaultPrintSettings = PrintSettings{
+	Paper:   "letter",
+	Margins: Margins{Top: 1, Bottom: 1, Left: 0.4, Right: 0.4},
+	Scale:   1,
+}
+
+func (p *PrintSettings) UnmarshalYAML(n *yaml.Node) error {
+	p.line = n.Line
+	type plain PrintSettings
+	return decodeStrict(n, (*plain)(p), "print settings")
+}
+
+func (p *PrintSettings) validate() error {
+	if _, ok := paperSizes[p.Paper]; !ok {
+		return fmt.Errorf("unknown paper size '%s' (available: %s)", p.Paper, strings.Join(slices.Sorted(maps.Keys(paperSizes)), ", "))
+	}
+	if p.Scale < 0.1 || p.Scale > 2 {
+		return fmt.Errorf("scale %g is not between 0.1 and 2", p.Scale)
+	}
+	m := p.Margins
+	if min(m.Top, m.Bottom, m.Left, m.Right) < 0 {
+		return fmt.Errorf("margins must not be negative")
+	}
+	w, h := p.size()
+	if (m.Left+m.Right)*72 >= w || (m.Top+m.Bottom)*72 >= h {
+		return fmt.Errorf("margins are larger than the %s paper", p.Paper)
+	}
+	return nil
+}
+
+// size returns the width and height of the paper in points, swapped in
+// landscape orientation.
+func (p *PrintSettings) size() (w, h float64) {
+	s := paperSizes[p.Paper]
+	if p.Landscape {
+		return s[1], s[0]
+	}
+	return s[0], s[1]
+}
+
+// Margins are the margins of a page. In the data file a single number
+// sets all four; margins left out of a mapping keep their default.
+type Margins struct {
+	Top    float64 `yaml:"top"`
+	Bottom float64 `yaml:"bottom"`
+	Left   float64 `yaml:"left"`
+	Right  float64 `yaml:"right"`
+}
+
+func (m *Margins) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		var v float64
+		if err := n.Decode(&v); err != nil {
+			return err
+		}
+		*m = Margins{v, v, v, v}
+		return nil
+	}
+	type plain Margins
+	return decodeStrict(n, (*plain)(m), "margins")
+}
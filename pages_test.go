@@ -0,0 +1,168 @@
+package main
+
+import (
+	"html"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestExpandPage(t *testing.T) {
+	r := &rendering{
+		Owner: Owner{Name: "Jane <Doe> & Co"},
+		Date:  time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+	}
+	values := r.pageValues("3", "7", html.EscapeString)
+	for _, tt := range []struct{ in, want string }{
+		{"", ""},
+		{"{name}", "Jane &lt;Doe&gt; &amp; Co"},
+		{"{date}", "May 6, 2024"},
+		{"Page {page} of {pages}", "Page 3 of 7"},
+		{"{page}{pages}", "37"},
+		{"a < b & {page}", "a &lt; b &amp; 3"},
+		{"{{page}}", "{3}"},
+		{"{nope} {} {Page}", "{nope} {} {Page}"},
+	} {
+		if got := expandPage(tt.in, values, html.EscapeString); got != tt.want {
+			t.Errorf("expandPage(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestChromePageTemplate(t *testing.T) {
+	r := &rendering{
+		Owner: Owner{Name: "Jane Doe"},
+		Date:  time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+	}
+	r.Print = defaultPrintSettings
+	r.Print.Margins.Left = 0.75
+	if got := r.chromePageTemplate(nil); got != "" {
+		t.Errorf("chromePageTemplate(nil) = %q, want \"\"", got)
+	}
+	got := r.chromePageTemplate(&PageText{Left: "{name}", Center: "Page {page} of {pages}", Right: "<{date}>"})
+	want := `<div style="font-family:'Times New Roman',serif;font-size:9pt;width:100%;margin:0 0.4in 0 0.75in;display:flex">` +
+		`<span style="flex:1;text-align:left">Jane Doe</span>` +
+		`<span style="flex:1;text-align:center">Page <span class="pageNumber"></span> of <span class="totalPages"></span></span>` +
+		`<span style="flex:1;text-align:right">&lt;May 6, 2024&gt;</span>` +
+		`</div>`
+	if got != want {
+		t.Errorf("chromePageTemplate:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestPageText(t *testing.T) {
+	for _, tt := range []struct {
+		yaml string
+		want PageText
+		err  string
+	}{
+		{"footer: 'Page {page}'", PageText{Center: "Page {page}"}, ""},
+		{"footer: {left: '{name}', right: '{date}'}", PageText{Left: "{name}", Right: "{date}"}, ""},
+		{"footer: {left: '{name}', center: '{page} of {pages}', right: '{date}'}", PageText{Left: "{name}", Center: "{page} of {pages}", Right: "{date}"}, ""},
+		{"footer: 'Page {page} of {total}'", PageText{}, "unknown placeholder {total} (available: {name}, {date}, {page}, {pages})"},
+		{"footer: {right: '{Name}'}", PageText{}, "unknown placeholder {Name}"},
+		{"footer: {middle: x}", PageText{}, "line 1: field middle not found in page text"},
+		{"footer: [x]", PageText{}, "cannot unmarshal"},
+	} {
+		var v struct {
+			Footer PageText `yaml:"footer"`
+		}
+		err := yaml.Unmarshal([]byte(tt.yaml), &v)
+		if err == nil {
+			err = v.Footer.validate()
+		}
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want %q", tt.yaml, err, tt.err)
+			}
+			continue
+		}
+		v.Footer.line = 0
+		if err != nil {
+			t.Errorf("%s: %v", tt.yaml, err)
+		} else if !reflect.DeepEqual(v.Footer, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.yaml, v.Footer, tt.want)
+		}
+	}
+}
+
+func TestPrintSettings(t *testing.T) {
+	margins := func(top, bottom, left, right float64) PrintSettings {
+		p := defaultPrintSettings
+		p.Margins = Margins{top, bottom, left, right}
+		return p
+	}
+	a4 := defaultPrintSettings
+	a4.Paper = "a4"
+	for _, tt := range []struct {
+		yaml string
+		want PrintSettings
+		err  string
+	}{
+		{"id: cv", defaultPrintSettings, ""},
+		{"print: {paper: a4}", a4, ""},
+		{"print: {margins: 0.5}", margins(0.5, 0.5, 0.5, 0.5), ""},
+		{"print: {margins: {top: 0.5, bottom: 0.5, left: 0.5, right: 0.5}}", margins(0.5, 0.5, 0.5, 0.5), ""},
+		{"print: {margins: {left: 0}}", margins(1, 1, 0, 0.4), ""},
+		{"print: {margins: 0}", margins(0, 0, 0, 0), ""},
+		{"print: {margins: {top: 4.25, bottom: 4.25}}", margins(4.25, 4.25, 0.4, 0.4), ""},
+		{"print: {paper: b5}", PrintSettings{}, "unknown paper size 'b5' (available: a3, a4, a5, legal, letter, tabloid)"},
+		{"print: {scale: 0.1}", PrintSettings{}, ""},
+		{"print: {scale: 2}", PrintSettings{}, ""},
+		{"print: {scale: 0.05}", PrintSettings{}, "scale 0.05 is not between 0.1 and 2"},
+		{"print: {scale: 0}", PrintSettings{}, "scale 0 is not between 0.1 and 2"},
+		{"print: {scale: 2.5}", PrintSettings{}, "scale 2.5 is not between 0.1 and 2"},
+		{"print: {margins: -0.1}", PrintSettings{}, "margins must not be negative"},
+		{"print: {margins: {right: -1}}", PrintSettings{}, "margins must not be negative"},
+		{"print: {margins: 4.25}", PrintSettings{}, "margins are larger than the letter paper"},
+		{"print: {margins: {top: 5.5, bottom: 5.5}}", PrintSettings{}, "margins are larger than the letter paper"},
+		{"print: {margins: {top: 4.25, bottom: 4.25}, landscape: true}", PrintSettings{}, "margins are larger than the letter paper"},
+		{"print: {margins: {left: 4.5, right: 4.5}, landscape: true}", PrintSettings{}, ""},
+		{"print: {paper: a5, margins: 3}", PrintSettings{}, "margins are larger than the a5 paper"},
+		{"print: {margins: wide}", PrintSettings{}, "cannot unmarshal"},
+		{"print: {margins: {middle: 1}}", PrintSettings{}, "field middle not found in margins"},
+		{"print: {size: a4}", PrintSettings{}, "field size not found in print settings"},
+	} {
+		var doc Document
+		err := yaml.Unmarshal([]byte(tt.yaml), &doc)
+		if err == nil {
+			err = doc.Print.validate()
+		}
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want %q", tt.yaml, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.yaml, err)
+			continue
+		}
+		doc.Print.line = 0
+		if tt.want != (PrintSettings{}) && doc.Print != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.yaml, doc.Print, tt.want)
+		}
+	}
+}
+
+func TestPrintSettingsSize(t *testing.T) {
+	for _, tt := range []struct {
+		paper     string
+		landscape bool
+		w, h      float64
+	}{
+		{"letter", false, 612, 792},
+		{"letter", true, 792, 612},
+		{"a4", false, 595.28, 841.89},
+		{"a4", true, 841.89, 595.28},
+		{"tabloid", true, 1224, 792},
+	} {
+		p := PrintSettings{Paper: tt.paper, Landscape: tt.landscape}
+		if w, h := p.size(); w != tt.w || h != tt.h {
+			t.Errorf("%s (landscape %v): size %g×%g, want %g×%g", tt.paper, tt.landscape, w, h, tt.w, tt.h)
+		}
+	}
+}
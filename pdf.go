@@ -0,0 +1,386 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Layout of PDFs written by writePDF, in points at a scale of 1.
+const (
+	pdfBodySize    = 11
+	pdfHeadingSize = 14
+	pdfNameSize    = 24
+	pdfContactSize = 10.5
+	pdfPageSize    = 9   // page headers and footers
+	pdfLineHeight  = 1.3 // relative to the font size
+)
+
+// pdfPagesAlias is replaced with the number of pages when the PDF is
+// written.
+const pdfPagesAlias = "{nb}"
+
+// pdfFontFiles are the TrueType fonts that make up a font family for the
+// fonts flag of the build command, by style.
+var pdfFontFiles = []struct{ style, file string }{
+	{"", "regular.ttf"},
+	{"B", "bold.ttf"},
+	{"I", "italic.ttf"},
+	{"BI", "bolditalic.ttf"},
+}
+
+// writePDF lays out r directly as a PDF, as an alternative to printing the
+// HTML template with a browser. fontDir, if set, is a directory of
+// TrueType fonts to use instead of the built-in Times, which can only show
+// the characters of the Windows-1252 code page.
+func writePDF(r *rendering, fontDir, filename string) error {
+	p := r.Print
+	orientation := "P"
+	if p.Landscape {
+		orientation = "L"
+	}
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		OrientationStr: orientation,
+		UnitStr:        "pt",
+		Size:           fpdf.SizeType{Wd: paperSizes[p.Paper][0], Ht: paperSizes[p.Paper][1]},
+	})
+	m := p.Margins
+	pdf.SetMargins(m.Left*72, m.Top*72, m.Right*72)
+	pdf.SetAutoPageBreak(true, m.Bottom*72)
+	pdf.SetCellMargin(0)
+	for _, e := range r.pdfInfo() {
+		switch e[0] {
+		case "Title":
+			pdf.SetTitle(e[1], true)
+		case "Author":
+			pdf.SetAuthor(e[1], true)
+		case "Subject":
+			pdf.SetSubject(e[1], true)
+		case "Keywords":
+			pdf.SetKeywords(e[1], true)
+		}
+	}
+	w := &pdfWriter{
+		Fpdf:    pdf,
+		family:  "Times",
+		tr:      pdf.UnicodeTranslatorFromDescriptor(""),
+		scale:   p.Scale,
+		margins: m,
+	}
+	if fontDir != "" {
+		regular, err := os.ReadFile(filepath.Join(fontDir, pdfFontFiles[0].file))
+		if err != nil {
+			return err
+		}
+		for _, f := range pdfFontFiles {
+			b, err := os.ReadFile(filepath.Join(fontDir, f.file))
+			if err != nil {
+				// Fall back to the regular font for missing styles.
+				b = regular
+			}
+			pdf.AddUTF8FontFromBytes("serif", f.style, b)
+		}
+		if err := pdf.Error(); err != nil {
+			return fmt.Errorf("loading fonts from %s: %v", fontDir, err)
+		}
+		w.family = "serif"
+		w.tr = func(s string) string { return s }
+		w.utf8 = true
+	}
+	if r.Header != nil || r.Footer != nil {
+		pdf.AliasNbPages(pdfPagesAlias)
+		pdf.SetHeaderFunc(func() { w.pageText(r, r.Header, m.Top*72/2) })
+		pdf.SetFooterFunc(func() {
+			_, pageHeight := pdf.GetPageSize()
+			w.pageText(r, r.Footer, pageHeight-m.Bottom*72/2)
+		})
+	}
+	pdf.AddPage()
+
+	w.header(r.Owner)
+	for _, s := range r.Sections {
+		if err := w.section(r, s); err != nil {
+			return err
+		}
+	}
+	return pdf.OutputFileAndClose(filename)
+}
+
+// pdfWriter writes the parts of a CV to a PDF.
+type pdfWriter struct {
+	*fpdf.Fpdf
+	family string
+	tr     func(string) string // converts text to the encoding of the font
+	utf8   bool                // whether the font is a UTF-8 font
+
+	scale   float64 // of sizes and spacing
+	margins Margins // of the page, in inches
+}
+
+// pt returns v points at the scale of w.
+func (w *pdfWriter) pt(v float64) float64 {
+	return v * w.scale
+}
+
+// header writes the owner's name on the left and their contact details on
+// the right, followed by a rule.
+func (w *pdfWriter) header(o Owner) {
+	left, top, right, _ := w.GetMargins()
+	pageWidth, _ := w.GetPageSize()
+	w.SetFont(w.family, "", w.pt(pdfNameSize))
+	w.SetXY(left, top)
+	w.CellFormat(0, w.pt(pdfNameSize*pdfLineHeight), w.tr(o.Name), "", 0, "L", false, 0, "")
+	bottom := top + w.pt(pdfNameSize*pdfLineHeight)
+
+	// The lines are placed to end at the margin, so they must not wrap
+	// there because of rounding.
+	w.SetRightMargin(0)
+	y := top
+	for _, l := range o.contactLines() {
+		line := parseFragment(l)
+		w.SetXY(pageWidth-right-w.width(line, w.pt(pdfContactSize)), y)
+		w.write(line, w.pt(pdfContactSize), false)
+		y += w.pt(pdfContactSize * pdfLineHeight)
+	}
+	w.SetRightMargin(right)
+	bottom = max(bottom, y) + w.pt(6)
+	w.SetDrawColor(200, 200, 200)
+	w.Line(left, bottom, pageWidth-right, bottom)
+	w.SetY(bottom + w.pt(12))
+}
+
+// pageText writes p, if set, on a line centered vertically on y, and
+// returns to the top margin. The margins may be indented by the content
+// that is interrupted by the page break, so p is aligned with the page
+// instead.
+func (w *pdfWriter) pageText(r *rendering, p *PageText, y float64) {
+	left, top, _, _ := w.GetMargins()
+	if p != nil {
+		pageWidth, _ := w.GetPageSize()
+		same := func(s string) string { return s }
+		values := r.pageValues(strconv.Itoa(w.PageNo()), pdfPagesAlias, same)
+		lh := w.pt(pdfPageSize * pdfLineHeight)
+		w.SetFont(w.family, "", w.pt(pdfPageSize))
+		w.SetTextColor(100, 100, 100)
+		for i, s := range p.parts() {
+			if s == "" {
+				continue
+			}
+			left, right := w.margins.Left*72, w.margins.Right*72
+			w.SetXY(left, y-lh/2)
+			w.CellFormat(pageWidth-left-right, lh, w.tr(expandPage(s, values, same)), "", 0, []string{"L", "C", "R"}[i], false, 0, "")
+		}
+		w.SetTextColor(0, 0, 0)
+	}
+	w.SetXY(left, top)
+}
+
+// section writes the heading and contents of s, indented as in the HTML
+// template.
+func (w *pdfWriter) section(r *rendering, s Section) error {
+	left, _, right, _ := w.GetMargins()
+	pageWidth, _ := w.GetPageSize()
+	indent := (pageWidth - left - right) / 12
+	body, heading := w.pt(pdfBodySize), w.pt(pdfHeadingSize)
+	lh := body * pdfLineHeight
+
+	w.keep(heading*pdfLineHeight + 2*lh)
+	w.SetX(left)
+	w.bookmark(plainText(parseFragment(string(s.Name))))
+	w.write(parseFragment(string(s.Name)), heading, true)
+	w.write(parseFragment(string(r.legend(s.Citations, s.Authors))), heading, false)
+	w.Ln(heading * pdfLineHeight)
+	w.SetY(w.GetY() + w.pt(4))
+
+	var groups []citationGroup
+	if len(s.Citations) > 0 {
+		groups = r.groups(s)
+	}
+	for _, g := range groups {
+		if s.GroupByYear {
+			w.keep(3 * lh)
+			w.SetFont(w.family, "B", body)
+			w.SetX(left + indent)
+			w.CellFormat(0, lh, w.tr(g.Year), "", 1, "L", false, 0, "")
+		}
+		w.SetFont(w.family, "", body)
+		numWidth := w.GetStringWidth(fmt.Sprintf("%d.", g.Start))
+		for i, key := range g.Citations {
+			text, err := r.ref(key, s.Authors)
+			if err != nil {
+				return err
+			}
+			w.keep(2 * lh)
+			y := w.GetY()
+			w.SetFont(w.family, "", body)
+			w.SetXY(left+indent-numWidth-w.pt(4), y)
+			w.CellFormat(numWidth, lh, fmt.Sprintf("%d.", g.Start-i), "", 0, "R", false, 0, "")
+			w.SetLeftMargin(left + indent)
+			w.SetXY(left+indent, y)
+			w.write(parseFragment(string(text)), body, false)
+			w.Ln(lh)
+			w.SetLeftMargin(left)
+			w.SetY(w.GetY() + 0.5*lh)
+		}
+	}
+
+	timeWidth := (pageWidth - left - right) / 4
+	for _, item := range s.Items {
+		w.keep(2 * lh)
+		y := w.GetY()
+		if item.Time != "" {
+			t := parseFragment(string(item.Time))
+			w.SetXY(pageWidth-right-w.width(t, body), y)
+			w.write(t, body, false)
+			w.SetRightMargin(right + timeWidth)
+		}
+		w.SetLeftMargin(left + indent)
+		w.SetXY(left+indent, y)
+		w.write(parseFragment(string(item.Name)), body, false)
+		if item.Description != "" {
+			w.Ln(lh)
+			w.write(parseFragment(string(item.Description)), body, false)
+		}
+		w.Ln(lh)
+		w.SetMargins(left, w.margins.Top*72, right)
+		w.SetY(w.GetY() + 0.5*lh)
+	}
+	w.SetY(w.GetY() + lh)
+	return nil
+}
+
+// bookmark adds an entry for text at the current position to the outline
+// of the PDF.
+func (w *pdfWriter) bookmark(text string) {
+	if !w.utf8 {
+		// fpdf only encodes the text for UTF-8 fonts; outline entries
+		// are otherwise taken to be in PDFDocEncoding, not Windows-1252.
+		var b strings.Builder
+		b.WriteString("\xfe\xff")
+		for _, c := range utf16.Encode([]rune(text)) {
+			b.WriteByte(byte(c >> 8))
+			b.WriteByte(byte(c))
+		}
+		text = b.String()
+	}
+	w.Bookmark(text, 0, -1)
+}
+
+// keep starts a new page unless there is room for height points on the
+// current one.
+func (w *pdfWriter) keep(height float64) {
+	_, pageHeight := w.GetPageSize()
+	_, _, _, bottom := w.GetMargins()
+	if w.GetY()+height > pageHeight-bottom {
+		w.AddPage()
+	}
+}
+
+// A pdfSpan is text to be written in one font size.
+type pdfSpan struct {
+	run    textRun
+	text   string
+	size   float64
+	offset float64 // raise above the baseline, in points
+}
+
+// spans splits runs into spans, emulating small capitals if smallCaps is
+// true by setting lower case letters as smaller capitals.
+func spans(runs []textRun, size float64, smallCaps bool) []pdfSpan {
+	var out []pdfSpan
+	for _, run := range runs {
+		sz := size
+		if run.Small {
+			sz *= 0.85
+		}
+		var offset float64
+		switch {
+		case run.Sup:
+			sz, offset = sz*0.7, size*0.35
+		case run.Sub:
+			sz, offset = sz*0.7, -size*0.15
+		}
+		if !smallCaps || run.Break {
+			out = append(out, pdfSpan{run, run.Text, sz, offset})
+			continue
+		}
+		text := []rune(run.Text)
+		for len(text) > 0 {
+			lower := unicode.IsLower(text[0])
+			n := 1
+			for n < len(text) && unicode.IsLower(text[n]) == lower {
+				n++
+			}
+			if lower {
+				out = append(out, pdfSpan{run, strings.ToUpper(string(text[:n])), sz * 0.8, offset})
+			} else {
+				out = append(out, pdfSpan{run, string(text[:n]), sz, offset})
+			}
+			text = text[n:]
+		}
+	}
+	return out
+}
+
+// setFont selects the font for run at size points.
+func (w *pdfWriter) setFont(run textRun, size float64) {
+	style := ""
+	if run.Bold {
+		style += "B"
+	}
+	if run.Italic {
+		style += "I"
+	}
+	if run.Underline {
+		style += "U"
+	}
+	w.SetFont(w.family, style, size)
+}
+
+// write writes runs from the current position, wrapping lines at the
+// margins, with size as the main font size.
+func (w *pdfWriter) write(runs []textRun, size float64, smallCaps bool) {
+	lh := size * pdfLineHeight
+	for _, s := range spans(runs, size, smallCaps) {
+		if s.run.Break {
+			w.Ln(lh)
+			continue
+		}
+		w.setFont(s.run, size)
+		if s.run.Href != "" {
+			w.SetTextColor(0, 102, 204)
+		} else {
+			w.SetTextColor(0, 0, 0)
+		}
+		text := w.tr(s.text)
+		switch {
+		case s.size != size || s.offset != 0:
+			// SubWrite keeps smaller text on the baseline of the main size.
+			w.SubWrite(lh, text, s.size, s.offset, 0, s.run.Href)
+		case s.run.Href != "":
+			w.WriteLinkString(lh, text, s.run.Href)
+		default:
+			w.Write(lh, text)
+		}
+	}
+	w.SetTextColor(0, 0, 0)
+}
+
+// width returns the width of runs on a single line.
+func (w *pdfWriter) width(runs []textRun, size float64) float64 {
+	var total float64
+	for _, s := range spans(runs, size, false) {
+		if s.run.Break {
+			continue
+		}
+		w.setFont(s.run, s.size)
+		total += w.GetStringWidth(w.tr(s.text))
+	}
+	return total
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+// pdfPageObject matches the page objects of a PDF, but not its page tree.
+var pdfPageObject = regexp.MustCompile(`/Type /Page\b[^s]`)
+
+// TestWritePDF lays out the sample documents, and the full CV repeated to
+// fill several pages, and checks that the results are PDFs with the
+// expected number of pages.
+func TestWritePDF(t *testing.T) {
+	rs := testRenderings(t)
+	long := *rs[0]
+	long.ID = "long"
+	for range 5 {
+		long.Sections = append(long.Sections, rs[0].Sections...)
+	}
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		r     *rendering
+		pages int
+	}{
+		{rs[0], 1},
+		{rs[1], 1},
+		{&long, 4},
+	} {
+		f := filepath.Join(dir, tt.r.ID+".pdf")
+		if err := writePDF(tt.r, "", f); err != nil {
+			t.Fatalf("%s: %v", tt.r.ID, err)
+		}
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(b, []byte("%PDF-")) {
+			t.Errorf("%s: no PDF header: %q", tt.r.ID, b[:min(len(b), 16)])
+		}
+		if got := len(pdfPageObject.FindAll(b, -1)); got != tt.pages {
+			t.Errorf("%s: %d pages, want %d", tt.r.ID, got, tt.pages)
+		}
+	}
+}
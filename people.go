@@ -0,0 +1,265 @@
+package main
+
+import (
+	"html/template"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A Marker is a symbol added after a name in citations, along with the
+// legend that explains it.
+type Marker struct {
+	Symbol      string `yaml:"symbol"`      // added after the name, e.g. "†"
+	Superscript bool   `yaml:"superscript"` // whether Symbol is raised
+	Legend      string `yaml:"legend"`      // explanation shown in section headings
+}
+
+func (m *Marker) symbol() string {
+	if m.Symbol == "" {
+		return ""
+	}
+	if m.Superscript {
+		return "<sup>" + m.Symbol + "</sup>"
+	}
+	return m.Symbol
+}
+
+// Markers are the markers for corresponding authors and authors who
+// contributed equally. They are given by the corresponding and equalcontrib
+// fields of a BibTeX entry, which list the names of the authors concerned.
+// An asterisk after an author's name also marks a corresponding author.
+type Markers struct {
+	Corresponding Marker `yaml:"corresponding"`
+	EqualContrib  Marker `yaml:"equal-contribution"`
+}
+
+var defaultMarkers = Markers{
+	Corresponding: Marker{Symbol: "*", Legend: "corresponding author"},
+	EqualContrib:  Marker{Symbol: "†", Superscript: true, Legend: "equal contribution"},
+}
+
+// A Role is a category of people, such as advisees, whose names are
+// marked in citations.
+type Role struct {
+	ID        string `yaml:"id"`
+	Underline bool   `yaml:"underline"`
+	Bold      bool   `yaml:"bold"`
+	Marker    `yaml:",inline"`
+
+	line int
+}
+
+func (r *Role) UnmarshalYAML(n *yaml.Node) error {
+	type plain Role
+	if err := decodeStrict(n, (*plain)(r), "role"); err != nil {
+		return err
+	}
+	r.line = n.Line
+	return nil
+}
+
+// mark applies the role's markup to a formatted name.
+func (r *Role) mark(s string) string {
+	if r.Underline {
+		s = "<u>" + s + "</u>"
+	}
+	if r.Bold {
+		s = "<strong>" + s + "</strong>"
+	}
+	return s + r.symbol()
+}
+
+// A Person is someone whose name is marked in citations according to
+// their role. Names are matched on the parsed family name and given names
+// or initials, and only in entries published from From to To, if given.
+type Person struct {
+	Name    string   `yaml:"name"`
+	Aliases []string `yaml:"aliases"`
+	Role    string   `yaml:"role"`
+	From    int      `yaml:"from"`
+	To      int      `yaml:"to"`
+
+	line  int
+	role  *Role
+	names []name
+}
+
+func (p *Person) UnmarshalYAML(n *yaml.Node) error {
+	type plain Person
+	if err := decodeStrict(n, (*plain)(p), "person"); err != nil {
+		return err
+	}
+	p.line = n.Line
+	return nil
+}
+
+// matches reports whether n, in an entry published in year, is p.
+func (p *Person) matches(n name, year int) bool {
+	if p.From != 0 && year < p.From || p.To != 0 && year > p.To {
+		return false
+	}
+	for _, pn := range p.names {
+		if sameName(pn, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameName reports whether a and b can be the same person: the family
+// names must be equal, the initials must agree as far as both are given,
+// and the first given names must be equal if both are written out.
+func sameName(a, b name) bool {
+	if a.Corporate || b.Corporate {
+		return a.Corporate == b.Corporate && strings.EqualFold(a.Last, b.Last)
+	}
+	if !strings.EqualFold(stripTags(a.family()), stripTags(b.family())) {
+		return false
+	}
+	ia, ib := initialList(a), initialList(b)
+	if len(ia) > 0 && len(ib) == 0 {
+		return false
+	}
+	for i := 0; i < len(ia) && i < len(ib); i++ {
+		if !strings.EqualFold(ia[i], ib[i]) {
+			return false
+		}
+	}
+	fa, fb := firstGiven(a), firstGiven(b)
+	return fa == "" || fb == "" || strings.EqualFold(fa, fb)
+}
+
+// initialList splits the initials of n, e.g. "J.-P.W." into "J", "-P"
+// and "W".
+func initialList(n name) []string {
+	var out []string
+	for _, s := range strings.Split(n.Initials, ".") {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// firstGiven returns the first given name of n if it is written out, or
+// "" if it is an initial.
+func firstGiven(n name) string {
+	f := strings.Fields(stripTags(n.First))
+	if len(f) == 0 || len([]rune(f[0])) == 1 || strings.Contains(f[0], ".") {
+		return ""
+	}
+	return f[0]
+}
+
+// nameMarks holds what is marked on the names in citations: the people
+// in the data file and the author markers.
+type nameMarks struct {
+	people  []Person
+	markers *Markers
+}
+
+// markPeople sets the role of each name that belongs to someone in people.
+func markPeople(names []name, people []Person, year int) {
+	for i := range names {
+		for j := range people {
+			if people[j].matches(names[i], year) {
+				names[i].Role = people[j].role
+				break
+			}
+		}
+	}
+}
+
+// markAuthors adds the corresponding and equal contribution markers to
+// authors, given the names listed in the corresponding and equalcontrib
+// fields.
+func markAuthors(authors []name, markers *Markers, corresponding, equalContrib []name) {
+	for i := range authors {
+		n := &authors[i]
+		if n.Corresponding || containsName(corresponding, *n) {
+			n.Markers = append(n.Markers, &markers.Corresponding)
+		}
+		if containsName(equalContrib, *n) {
+			n.Markers = append(n.Markers, &markers.EqualContrib)
+		}
+	}
+}
+
+func containsName(names []name, n name) bool {
+	for _, m := range names {
+		if sameName(m, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// markName applies the markup for the role of n and its markers to s, the
+// formatted name.
+func markName(s string, n name) string {
+	if n.Role != nil {
+		s = n.Role.mark(s)
+	}
+	for _, m := range n.Markers {
+		s += m.symbol()
+	}
+	return s
+}
+
+// legend explains the roles and markers used in a list of citations, for
+// use in a section heading.
+func (r *rendering) legend(keys []template.HTML, authors *Truncation) template.HTML {
+	used := make(map[*Marker]bool)
+	var roles []*Role
+	for _, k := range keys {
+		e, ok := r.citations[k]
+		if !ok {
+			continue
+		}
+		e = e.withMarks(r.marks).withAuthors(authors)
+		for _, f := range []string{"author", "editor"} {
+			for _, n := range e.names(f) {
+				if role := n.Role; role != nil && !used[&role.Marker] {
+					used[&role.Marker] = true
+					roles = append(roles, role)
+				}
+				for _, mk := range n.Markers {
+					used[mk] = true
+				}
+			}
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].line < roles[j].line })
+	// Roles that are marked the same way share a part of the legend.
+	var samples []string
+	legends := make(map[string][]string)
+	for _, role := range roles {
+		if role.Legend == "" {
+			continue
+		}
+		sample := role.symbol()
+		if sample == "" {
+			sample = role.mark("Name")
+		}
+		if legends[sample] == nil {
+			samples = append(samples, sample)
+		}
+		legends[sample] = append(legends[sample], role.Legend)
+	}
+	var parts []string
+	for _, sample := range samples {
+		parts = append(parts, sample+"="+strings.Join(legends[sample], " and "))
+	}
+	markers := r.marks.markers
+	for _, mk := range []*Marker{&markers.Corresponding, &markers.EqualContrib} {
+		if used[mk] && mk.Legend != "" && mk.Symbol != "" {
+			parts = append(parts, mk.symbol()+"="+mk.Legend)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return template.HTML(` <small class="legend">(` + strings.Join(parts, "; ") + ")</small>")
+}
@@ -0,0 +1,70 @@
+package main
+
+import "testing"
+
+func TestSameName(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want bool
+	}{
+		{"Tessum, Christopher W.", "Tessum, C.W.", true},
+		{"Tessum, Christopher W.", "Tessum, C.", true},
+		{"Tessum, Christopher W.", "Christopher Tessum", true},
+		{"Tessum, Christopher W.", "TESSUM, christopher", true},
+		{"Tessum, Christopher W.", "Tessum, Michael", false},
+		{"Tessum, Christopher W.", "Tessum, M.W.", false},
+		{"Tessum, Christopher W.", "Tessum, C.J.", false},
+		{"Tessum, C.", "Tessum, Chris", true},
+		{"Tessum, Christopher", "Tessum, Chris", false},
+		{"Tessum, Christopher", "Tessum", false},
+		{"Tessum", "Tessum, C.", true},
+		{"van der Berg, Jan", "Berg, Jan", false},
+		{"van der Berg, Jan", "Jan van der Berg", true},
+		{`M{\"u}ller, J.`, "Müller, Jan", true},
+		{"{World Health Organization}", "{World Health Organization}", true},
+		{"{World Health Organization}", "Organization, World Health", false},
+	} {
+		a, b := parseName(tt.a), parseName(tt.b)
+		if got := sameName(a, b); got != tt.want {
+			t.Errorf("sameName(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMarkPeople(t *testing.T) {
+	self := &Role{ID: "self", Underline: true}
+	advisee := &Role{ID: "advisee", Marker: Marker{Symbol: "‡"}}
+	people := []Person{
+		{role: self, names: parseNames("Tessum, Christopher W.")},
+		{role: advisee, From: 2018, To: 2022, names: parseNames("Thakrar, Sumil K. and Thakrar, S.")},
+	}
+	for _, tt := range []struct {
+		year int
+		want []*Role
+	}{
+		{2017, []*Role{self, nil, nil}},
+		{2018, []*Role{self, advisee, nil}},
+		{2022, []*Role{self, advisee, nil}},
+		{2023, []*Role{self, nil, nil}},
+	} {
+		names := parseNames("Tessum, C.W. and Thakrar, S.K. and Hill, Jason")
+		markPeople(names, people, tt.year)
+		for i, n := range names {
+			if n.Role != tt.want[i] {
+				t.Errorf("%d: %s has role %v, want %v", tt.year, n.Last, n.Role, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestMarkAuthors(t *testing.T) {
+	markers := defaultMarkers
+	authors := parseNames("Tessum, Christopher W.* and Thakrar, Sumil and Hill, Jason and Marshall, J.")
+	markAuthors(authors, &markers, parseNames("Marshall, Julian D."), parseNames("Tessum, C. and Thakrar, S."))
+	want := []string{"Tessum*<sup>†</sup>", "Thakrar<sup>†</sup>", "Hill", "Marshall*"}
+	for i, n := range authors {
+		if got := markName(n.Last, n); got != want[i] {
+			t.Errorf("%d: got %q, want %q", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// A textRun is a piece of uniformly formatted text from an HTML fragment,
+// such as a formatted citation or a section name, for output formats
+// other than HTML.
+type textRun struct {
+	Text                           string
+	Italic, Bold, Underline, Small bool
+	Sup, Sub                       bool
+	Href                           string // link target, if any
+
+	// Break is true for a line break, which has no text.
+	Break bool
+}
+
+// parseFragment splits an HTML fragment into runs. It understands the
+// inline markup used in the data file and by the citation styles (i, em,
+// b, strong, u, small, sup, sub, a and br); other tags are dropped but
+// their text is kept. Whitespace is collapsed as a browser would.
+func parseFragment(s string) []textRun {
+	var (
+		runs  []textRun
+		depth = make(map[string]int)
+		hrefs []string
+		space = true // whether the last character written was a space
+	)
+	flush := func(text string) {
+		var b strings.Builder
+		for _, r := range html.UnescapeString(text) {
+			if unicode.IsSpace(r) && r != '\u00a0' {
+				if !space {
+					b.WriteRune(' ')
+				}
+				space = true
+				continue
+			}
+			b.WriteRune(r)
+			space = false
+		}
+		if b.Len() == 0 {
+			return
+		}
+		run := textRun{
+			Text:      b.String(),
+			Italic:    depth["i"]+depth["em"] > 0,
+			Bold:      depth["b"]+depth["strong"] > 0,
+			Underline: depth["u"] > 0,
+			Small:     depth["small"] > 0,
+			Sup:       depth["sup"] > 0,
+			Sub:       depth["sub"] > 0,
+		}
+		if len(hrefs) > 0 {
+			run.Href = hrefs[len(hrefs)-1]
+		}
+		// Merge with the previous run if the formatting is the same.
+		if n := len(runs); n > 0 && !runs[n-1].Break && sameFormat(runs[n-1], run) {
+			runs[n-1].Text += run.Text
+			return
+		}
+		runs = append(runs, run)
+	}
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			flush(s)
+			break
+		}
+		flush(s[:i])
+		s = s[i:]
+		j := strings.IndexByte(s, '>')
+		if j < 0 {
+			flush(s)
+			break
+		}
+		tag := s[1:j]
+		s = s[j+1:]
+		closing := strings.HasPrefix(tag, "/")
+		tag = strings.TrimPrefix(tag, "/")
+		// A slash that ends a tag is part of an unquoted attribute value,
+		// as in <a href=https://example.com/>, unless it follows a space
+		// or the name.
+		if strings.HasSuffix(tag, " /") || !strings.Contains(tag, " ") {
+			tag = strings.TrimSuffix(tag, "/")
+		}
+		name, attrs, _ := strings.Cut(strings.TrimSpace(tag), " ")
+		name = strings.ToLower(name)
+		switch {
+		case name == "br":
+			runs = append(runs, textRun{Break: true})
+			space = true
+		case name == "a" && closing:
+			if len(hrefs) > 0 {
+				hrefs = hrefs[:len(hrefs)-1]
+			}
+		case name == "a":
+			hrefs = append(hrefs, attr(attrs, "href"))
+		case closing:
+			if depth[name] > 0 {
+				depth[name]--
+			}
+		default:
+			depth[name]++
+		}
+	}
+	// Drop a trailing space.
+	if n := len(runs); n > 0 && !runs[n-1].Break {
+		runs[n-1].Text = strings.TrimRightFunc(runs[n-1].Text, unicode.IsSpace)
+		if runs[n-1].Text == "" {
+			runs = runs[:n-1]
+		}
+	}
+	return runs
+}
+
+// plainText returns the text of runs without formatting, with line
+// breaks as spaces.
+func plainText(runs []textRun) string {
+	var b strings.Builder
+	for _, run := range runs {
+		if run.Break {
+			b.WriteString(" ")
+		}
+		b.WriteString(run.Text)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func sameFormat(a, b textRun) bool {
+	a.Text, b.Text = "", ""
+	return a == b
+}
+
+// attr returns the value of the named attribute in the attributes of an
+// HTML tag, which may be quoted or not.
+func attr(attrs, name string) string {
+	for attrs != "" {
+		attrs = strings.TrimSpace(attrs)
+		k, rest, ok := strings.Cut(attrs, "=")
+		if !ok {
+			return ""
+		}
+		k = strings.ToLower(strings.TrimSpace(k))
+		rest = strings.TrimSpace(rest)
+		var v string
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			end := strings.IndexByte(rest[1:], rest[0])
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			v, attrs = rest[1:end+1], rest[min(end+2, len(rest)):]
+		} else {
+			v, attrs, _ = strings.Cut(rest, " ")
+		}
+		if k == name {
+			return html.UnescapeString(v)
+		}
+	}
+	return ""
+}
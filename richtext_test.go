@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFragment(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want []textRun
+	}{
+		{"", nil},
+		{"  plain   text \n", []textRun{{Text: "plain text"}}},
+		{"a <i>b</i> <em>c</em>", []textRun{{Text: "a "}, {Text: "b", Italic: true}, {Text: " "}, {Text: "c", Italic: true}}},
+		{"<strong><i>x</i></strong><b>y</b>", []textRun{{Text: "x", Italic: true, Bold: true}, {Text: "y", Bold: true}}},
+		{"CO<sub>2</sub> m<SUP>3</SUP>", []textRun{{Text: "CO"}, {Text: "2", Sub: true}, {Text: " m"}, {Text: "3", Sup: true}}},
+		{"<u>Name</u>*, <small>(x)</small>", []textRun{{Text: "Name", Underline: true}, {Text: "*, "}, {Text: "(x)", Small: true}}},
+		{"one<br>two<br/>three", []textRun{{Text: "one"}, {Break: true}, {Text: "two"}, {Break: true}, {Text: "three"}}},
+		{"&amp; &lt;tag&gt;&nbsp;x", []textRun{{Text: "& <tag> x"}}},
+		{"<span style='x'>kept</span> <div>text</div>", []textRun{{Text: "kept text"}}},
+		{`<a href="https://a.org/?x=1&amp;y=2">A</a> and <a href=https://b.org/>B</a>`,
+			[]textRun{{Text: "A", Href: "https://a.org/?x=1&y=2"}, {Text: " and "}, {Text: "B", Href: "https://b.org/"}}},
+		{"<a href=https://b.org/ >B</a>", []textRun{{Text: "B", Href: "https://b.org/"}}},
+		{"<a href=x><i>in</i> link</a>", []textRun{{Text: "in", Italic: true, Href: "x"}, {Text: " link", Href: "x"}}},
+		{"unclosed <i>italic", []textRun{{Text: "unclosed "}, {Text: "italic", Italic: true}}},
+		{"stray </i>close", []textRun{{Text: "stray close"}}},
+		{"a < b", []textRun{{Text: "a < b"}}},
+	} {
+		if got := parseFragment(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseFragment(%q) =\n %+v, want\n %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAttr(t *testing.T) {
+	for _, tt := range []struct{ attrs, name, want string }{
+		{`href="https://a.org/x y"`, "href", "https://a.org/x y"},
+		{`href='a' title="t"`, "title", "t"},
+		{`class=x HREF=https://a.org/`, "href", "https://a.org/"},
+		{`href = "a"`, "href", "a"},
+		{`href="a&amp;b"`, "href", "a&b"},
+		{`href="unterminated`, "href", "unterminated"},
+		{`title="t"`, "href", ""},
+		{"", "href", ""},
+	} {
+		if got := attr(tt.attrs, tt.name); got != tt.want {
+			t.Errorf("attr(%q, %q) = %q, want %q", tt.attrs, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,708 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Style formats a bibliography entry as an HTML fragment.
+type Style interface {
+	Format(e *bibEntry) (string, error)
+}
+
+// styles are the built-in citation styles, selectable by name with the
+// style field of a document. The style field may instead be the path of a
+// CSL style file ending in .csl.
+var styles = map[string]Style{
+	"default": defaultStyle{},
+	"apa":     apaStyle{},
+	"chicago": chicagoStyle{},
+	"acs":     acsStyle{},
+}
+
+const defaultStyleName = "default"
+
+func lookupStyle(name string) (Style, error) {
+	if name == "" {
+		name = defaultStyleName
+	}
+	if s, ok := styles[name]; ok {
+		return s, nil
+	}
+	if strings.HasSuffix(name, ".csl") {
+		return loadCSL(name)
+	}
+	names := make([]string, 0, len(styles))
+	for n := range styles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown citation style '%s' (available: %s, or a .csl file)", name, strings.Join(names, ", "))
+}
+
+// defaultStyle is the original format of this CV.
+type defaultStyle struct{}
+
+func (defaultStyle) Format(e *bibEntry) (string, error) {
+	switch e.Type {
+	case "article":
+		return parseArticle(e), nil
+	case "inproceedings":
+		return parseProceedings(e), nil
+	case "techreport":
+		return parseReport(e), nil
+	case "incollection":
+		return parseCollection(e), nil
+	case "book", "collection", "proceedings", "booklet", "manual":
+		return parseBook(e), nil
+	case "inbook":
+		return parseInBook(e), nil
+	case "phdthesis", "mastersthesis", "thesis":
+		return parseThesis(e), nil
+	case "misc", "software", "dataset":
+		return parseMisc(e), nil
+	case "unpublished":
+		return parseUnpublished(e), nil
+	case "patent":
+		return parsePatent(e), nil
+	case "online":
+		return parseOnline(e), nil
+	default:
+		return "", fmt.Errorf("invalid citation type %s", e.Type)
+	}
+}
+
+// A reference holds the parts of a bibliography entry that the built-in
+// styles use, decoded and with surrounding punctuation removed.
+type reference struct {
+	Type                         string
+	Authors, Editors             []name
+	Year, Month, Day             string
+	Title, Container             string
+	Volume, Issue, Pages         string
+	Publisher, Institution, Note string
+	Address                      string
+	URL, Link                    string
+	Edition, Chapter, Version    string
+	HowPublished                 string
+
+	// Kind is the type field, such as "PhD dissertation" or "U.S. Patent".
+	Kind string
+	// Accessed is the date on which an online source was visited.
+	Accessed string
+}
+
+func newReference(e *bibEntry) reference {
+	r := reference{
+		Type:         e.Type,
+		Authors:      e.names("author"),
+		Editors:      e.names("editor"),
+		Year:         e.year(),
+		Month:        monthName(e.field("month")),
+		Day:          e.field("day"),
+		Title:        strings.TrimRight(e.field("title"), "."),
+		Volume:       e.field("volume"),
+		Issue:        e.field("number"),
+		Pages:        e.field("pages"),
+		Publisher:    e.field("publisher"),
+		Institution:  e.field("institution"),
+		Note:         e.field("note"),
+		Address:      e.field("address"),
+		URL:          strings.TrimSpace(e.field("url")),
+		Link:         e.link(""),
+		Edition:      editionLabel(e.field("edition")),
+		Chapter:      e.field("chapter"),
+		Version:      e.field("version"),
+		HowPublished: e.field("howpublished"),
+		Kind:         e.field("type"),
+		Accessed:     formatDate(e.field("urldate")),
+	}
+	if !e.has("year") && e.has("date") {
+		d, _ := parseISODate(e.field("date"))
+		if d.month > 0 {
+			r.Month = monthNames[d.month-1]
+		}
+		if d.day > 0 {
+			r.Day = strconv.Itoa(d.day)
+		}
+	}
+	for _, f := range []string{"school", "organization"} {
+		if r.Institution == "" {
+			r.Institution = e.field(f)
+		}
+	}
+	switch e.Type {
+	case "article":
+		r.Container = e.field("journal")
+	default:
+		r.Container = e.field("booktitle")
+	}
+	r.Container = strings.TrimRight(r.Container, ".")
+	// A BibLaTeX inbook is a titled part of a book, which is formatted
+	// like a chapter in an edited collection.
+	if r.Type == "inbook" && r.Container != "" {
+		r.Type = "incollection"
+	}
+	return r
+}
+
+// publisher returns the publisher, or the institution responsible for the
+// work if there is none.
+func (r reference) publisher() string {
+	if r.Publisher != "" {
+		return r.Publisher
+	}
+	return r.Institution
+}
+
+// kind returns the Kind of r, or the default for its type.
+func (r reference) kind(defaults map[string]string) string {
+	if r.Kind != "" {
+		return r.Kind
+	}
+	return defaults[r.Type]
+}
+
+// isBook reports whether r is formatted as a whole book.
+func (r reference) isBook() bool {
+	switch r.Type {
+	case "book", "collection", "proceedings", "booklet", "manual", "inbook":
+		return true
+	}
+	return false
+}
+
+// editionLabel formats an edition such as "2" or "second" as "2nd ed.".
+func editionLabel(ed string) string {
+	if ed == "" {
+		return ""
+	}
+	if n, err := strconv.Atoi(ed); err == nil {
+		ed = ordinal(n)
+	}
+	if strings.HasSuffix(ed, "ed.") || strings.HasSuffix(strings.ToLower(ed), "edition") {
+		return ed
+	}
+	return ed + " ed."
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// formatDate formats an ISO date such as 2023-01-05 as "January 5, 2023",
+// returning other dates unchanged.
+func formatDate(s string) string {
+	d, ok := parseISODate(s)
+	switch {
+	case !ok || d.month < 1 || d.month > 12:
+		return s
+	case d.day == 0:
+		return fmt.Sprintf("%s %d", monthNames[d.month-1], d.year)
+	}
+	return fmt.Sprintf("%s %d, %d", monthNames[d.month-1], d.day, d.year)
+}
+
+var monthNames = []string{"January", "February", "March", "April", "May", "June",
+	"July", "August", "September", "October", "November", "December"}
+
+// monthNumber returns the month (1–12) given as a number, an abbreviation
+// or a full name, or 0 if m is not a month.
+func monthNumber(m string) int {
+	m = strings.ToLower(strings.TrimSpace(m))
+	if n, err := strconv.Atoi(m); err == nil && n >= 1 && n <= 12 {
+		return n
+	}
+	if len(m) < 3 {
+		return 0
+	}
+	for i, name := range monthNames {
+		if strings.HasPrefix(strings.ToLower(name), m) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// monthName returns the full name of month m, or m itself if it is not
+// recognized.
+func monthName(m string) string {
+	if n := monthNumber(m); n > 0 {
+		return monthNames[n-1]
+	}
+	return m
+}
+
+// link returns the DOI or URL of r as an HTML link, or "".
+func (r reference) link() string {
+	return r.Link
+}
+
+// joinNames joins formatted names with sep, using last before the final
+// name. If there are exactly two names, pair is used instead.
+func joinNames(names []string, sep, pair, last string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + pair + names[1]
+	}
+	return strings.Join(names[:len(names)-1], sep) + last + names[len(names)-1]
+}
+
+// spacedInitials returns initials separated by spaces, e.g. "C. W.".
+func spacedInitials(n name) string {
+	return strings.Replace(strings.Replace(n.Initials, ".", ". ", -1), ". -", ".-", -1)
+}
+
+// invertedName formats n as "Family, I. I." or "Family, Given".
+func invertedName(n name, given string) string {
+	if n.Corporate {
+		return n.Last
+	}
+	s := n.family()
+	if given = strings.TrimSpace(given); given != "" {
+		s += ", " + given
+	}
+	if n.Jr != "" {
+		s += ", " + n.Jr
+	}
+	return s
+}
+
+// directName formats n as "Given Family".
+func directName(n name, given string) string {
+	if n.Corporate {
+		return n.Last
+	}
+	s := strings.TrimSpace(strings.TrimSpace(given) + " " + n.family())
+	if n.Jr != "" {
+		s += " " + n.Jr
+	}
+	return s
+}
+
+// sentence joins non-empty parts with spaces, ending each with a period
+// unless it already ends with punctuation.
+func sentence(parts ...string) string {
+	var out []string
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.ContainsAny(lastVisible(strings.TrimRight(p, "”’")), ".?!") {
+			p += "."
+		}
+		out = append(out, p)
+	}
+	return strings.Join(out, " ")
+}
+
+// lastVisible returns the last character of s that is not part of an HTML
+// tag.
+func lastVisible(s string) string {
+	t := stripTags(s)
+	if t == "" {
+		return ""
+	}
+	r := []rune(t)
+	return string(r[len(r)-1])
+}
+
+func italic(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "<i>" + s + "</i>"
+}
+
+func bold(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "<strong>" + s + "</strong>"
+}
+
+// apaStyle follows the 7th edition of the APA Publication Manual.
+type apaStyle struct{}
+
+func (apaStyle) authors(names []name) string {
+	names, etAl := trimOthers(names)
+	var out []string
+	for _, n := range names {
+		out = append(out, markName(invertedName(n, spacedInitials(n)), n))
+	}
+	if etAl {
+		return strings.Join(out, ", ") + ", et al."
+	}
+	return joinNames(out, ", ", ", & ", ", & ")
+}
+
+func (apaStyle) editors(names []name) string {
+	var out []string
+	for _, n := range names {
+		out = append(out, markName(directName(n, spacedInitials(n)), n))
+	}
+	s := joinNames(out, ", ", " & ", ", & ")
+	if len(names) == 1 {
+		return s + " (Ed.)"
+	}
+	return s + " (Eds.)"
+}
+
+// apaKinds are the descriptions APA gives to works of each type.
+var apaKinds = map[string]string{
+	"phdthesis":     "Doctoral dissertation",
+	"mastersthesis": "Master's thesis",
+	"thesis":        "Thesis",
+	"software":      "Computer software",
+	"dataset":       "Data set",
+	"unpublished":   "Unpublished manuscript",
+	"patent":        "Patent",
+}
+
+func (s apaStyle) Format(e *bibEntry) (string, error) {
+	r := newReference(e)
+	date := "(" + r.Year + ")"
+	if r.Year == "" {
+		date = "(n.d.)"
+	} else if (r.Type == "inproceedings" || r.Type == "online") && r.Month != "" {
+		date = "(" + r.Year + ", " + strings.TrimSpace(r.Month+" "+r.Day) + ")"
+	}
+	authors := s.authors(r.Authors)
+	if authors == "" && len(r.Editors) > 0 {
+		authors = s.authors(r.Editors) + " (Ed.)."
+		if len(r.Editors) > 1 {
+			authors = s.authors(r.Editors) + " (Eds.)."
+		}
+	}
+	// A work without authors or editors is listed by its title, which
+	// takes the place of the authors before the date.
+	cite := func(title string, parts ...string) string {
+		if authors == "" {
+			return sentence(append([]string{title, date}, parts...)...)
+		}
+		return sentence(append([]string{authors, date, title}, parts...)...)
+	}
+	switch {
+	case r.Type == "article":
+		src := italic(r.Container)
+		if r.Volume != "" {
+			src += ", " + italic(r.Volume)
+			if r.Issue != "" {
+				src += "(" + r.Issue + ")"
+			}
+		}
+		if r.Pages != "" {
+			src += ", " + r.Pages
+		}
+		return cite(r.Title, src, r.link()), nil
+	case r.Type == "inproceedings":
+		return cite(italic(r.Title)+" [Conference presentation]", joinNonEmpty(", ", r.Container, r.Address), r.link()), nil
+	case r.Type == "techreport":
+		return cite(italic(r.Title), r.Institution, r.link()), nil
+	case r.Type == "incollection":
+		in := "In " + italic(r.Container)
+		if len(r.Editors) > 0 {
+			in = "In " + s.editors(r.Editors) + ", " + italic(r.Container)
+		}
+		if r.Pages != "" {
+			in += " (pp. " + r.Pages + ")"
+		}
+		return cite(r.Title, in, r.Publisher, r.link()), nil
+	case r.isBook():
+		var notes []string
+		if r.Edition != "" {
+			notes = append(notes, r.Edition)
+		}
+		if r.Volume != "" {
+			notes = append(notes, "Vol. "+r.Volume)
+		}
+		if r.Chapter != "" {
+			notes = append(notes, "Chapter "+r.Chapter)
+		}
+		if r.Pages != "" {
+			notes = append(notes, "pp. "+r.Pages)
+		}
+		title := italic(r.Title)
+		if len(notes) > 0 {
+			title += " (" + strings.Join(notes, ", ") + ")"
+		}
+		return cite(title, r.publisher(), r.link()), nil
+	case r.Type == "phdthesis" || r.Type == "mastersthesis" || r.Type == "thesis":
+		return cite(italic(r.Title)+" ["+joinNonEmpty(", ", r.kind(apaKinds), r.Institution)+"]", r.link()), nil
+	case r.Type == "misc" || r.Type == "software" || r.Type == "dataset":
+		title := italic(r.Title)
+		if r.Version != "" {
+			title += " (Version " + r.Version + ")"
+		}
+		if k := r.kind(apaKinds); k != "" {
+			title += " [" + k + "]"
+		}
+		return cite(title, r.HowPublished, r.publisher(), r.Note, r.link()), nil
+	case r.Type == "unpublished":
+		return cite(italic(r.Title)+" ["+r.kind(apaKinds)+"]", r.Institution, r.Note, r.link()), nil
+	case r.Type == "patent":
+		title := italic(r.Title) + " (" + r.kind(apaKinds) + " No. " + r.Issue + ")"
+		return cite(title, r.publisher(), r.link()), nil
+	case r.Type == "online":
+		access := r.link()
+		if r.Accessed != "" && r.URL != "" {
+			access = "Retrieved " + r.Accessed + ", from " + fmt.Sprintf("<a href=%s>%s</a>", r.URL, r.URL)
+		}
+		return cite(italic(r.Title), joinNonEmpty(", ", r.Container, r.publisher()), access), nil
+	default:
+		return "", fmt.Errorf("invalid citation type %s", r.Type)
+	}
+}
+
+// chicagoStyle follows the author-date system of the Chicago Manual of
+// Style, 17th edition.
+type chicagoStyle struct{}
+
+func (chicagoStyle) authors(names []name) string {
+	names, etAl := trimOthers(names)
+	var out []string
+	for i, n := range names {
+		if i == 0 {
+			out = append(out, markName(invertedName(n, n.First), n))
+		} else {
+			out = append(out, markName(directName(n, n.First), n))
+		}
+	}
+	if etAl {
+		return strings.Join(out, ", ") + ", et al."
+	}
+	return joinNames(out, ", ", ", and ", ", and ")
+}
+
+func (chicagoStyle) editors(names []name) string {
+	var out []string
+	for _, n := range names {
+		out = append(out, markName(directName(n, n.First), n))
+	}
+	return joinNames(out, ", ", " and ", ", and ")
+}
+
+// chicagoKinds are the descriptions Chicago gives to works of each type.
+var chicagoKinds = map[string]string{
+	"phdthesis":     "PhD diss.",
+	"mastersthesis": "Master's thesis",
+	"thesis":        "Thesis",
+	"software":      "Software",
+	"dataset":       "Dataset",
+	"unpublished":   "Unpublished manuscript",
+	"patent":        "Patent",
+}
+
+func (s chicagoStyle) Format(e *bibEntry) (string, error) {
+	r := newReference(e)
+	year := r.Year
+	if year == "" {
+		year = "n.d."
+	}
+	authors := s.authors(r.Authors)
+	if authors == "" && len(r.Editors) > 0 {
+		authors = s.authors(r.Editors) + ", ed."
+		if len(r.Editors) > 1 {
+			authors += "s."
+		}
+	}
+	// A work without authors or editors is listed by its title, which
+	// takes the place of the authors before the year.
+	cite := func(title string, parts ...string) string {
+		if authors == "" {
+			return sentence(append([]string{title, year}, parts...)...)
+		}
+		return sentence(append([]string{authors, year, title}, parts...)...)
+	}
+	title := "“" + r.Title + ".”"
+	switch {
+	case r.Type == "article":
+		src := italic(r.Container)
+		if r.Volume != "" {
+			src += " " + r.Volume
+		}
+		if r.Issue != "" {
+			src += " (" + r.Issue + ")"
+		}
+		if r.Pages != "" {
+			src += ": " + r.Pages
+		}
+		return cite(title, src, r.link()), nil
+	case r.Type == "inproceedings":
+		return cite(title, "Paper presented at "+joinNonEmpty(", ", r.Container, r.Address), r.link()), nil
+	case r.Type == "techreport":
+		return cite(italic(r.Title), joinNonEmpty(": ", r.Address, r.Institution), r.link()), nil
+	case r.Type == "incollection":
+		in := "In " + italic(r.Container)
+		if len(r.Editors) > 0 {
+			in += ", edited by " + s.editors(r.Editors)
+		}
+		if r.Pages != "" {
+			in += ", " + r.Pages
+		}
+		return cite(title, in, joinNonEmpty(": ", r.Address, r.Publisher), r.link()), nil
+	case r.isBook():
+		var part string
+		if r.Volume != "" {
+			part = "Vol. " + r.Volume
+		}
+		if r.Chapter != "" {
+			part = joinNonEmpty(", ", part, "Chapter "+r.Chapter)
+		}
+		if r.Pages != "" {
+			part = joinNonEmpty(", ", part, r.Pages)
+		}
+		return cite(italic(r.Title), r.Edition, part, joinNonEmpty(": ", r.Address, r.publisher()), r.link()), nil
+	case r.Type == "phdthesis" || r.Type == "mastersthesis" || r.Type == "thesis":
+		return cite(title, joinNonEmpty(", ", r.kind(chicagoKinds), r.Institution), r.link()), nil
+	case r.Type == "misc" || r.Type == "software" || r.Type == "dataset":
+		version := ""
+		if r.Version != "" {
+			version = "Version " + r.Version
+		}
+		return cite(italic(r.Title), version, r.kind(chicagoKinds), r.HowPublished, r.publisher(), r.Note, r.link()), nil
+	case r.Type == "unpublished":
+		return cite(title, r.kind(chicagoKinds), r.Institution, r.Note, r.link()), nil
+	case r.Type == "patent":
+		return cite(title, r.kind(chicagoKinds)+" "+r.Issue, r.link()), nil
+	case r.Type == "online":
+		accessed := ""
+		if r.Accessed != "" {
+			accessed = "Accessed " + r.Accessed
+		}
+		return cite(title, joinNonEmpty(", ", r.Container, r.publisher()), accessed, r.link()), nil
+	default:
+		return "", fmt.Errorf("invalid citation type %s", r.Type)
+	}
+}
+
+// acsStyle follows the ACS Guide to Scholarly Communication.
+type acsStyle struct{}
+
+func (acsStyle) names(names []name) string {
+	names, etAl := trimOthers(names)
+	var out []string
+	for _, n := range names {
+		out = append(out, markName(invertedName(n, spacedInitials(n)), n))
+	}
+	if etAl {
+		out = append(out, "et al.")
+	}
+	return strings.Join(out, "; ")
+}
+
+// acsKinds are the descriptions ACS gives to works of each type.
+var acsKinds = map[string]string{
+	"phdthesis":     "Ph.D. Dissertation",
+	"mastersthesis": "M.S. Thesis",
+	"thesis":        "Thesis",
+	"software":      "Software",
+	"dataset":       "Data set",
+	"unpublished":   "Unpublished work",
+	"patent":        "Patent",
+}
+
+func (s acsStyle) Format(e *bibEntry) (string, error) {
+	r := newReference(e)
+	authors := s.names(r.Authors)
+	if authors == "" && len(r.Editors) > 0 {
+		authors = s.names(r.Editors) + ", Ed."
+		if len(r.Editors) > 1 {
+			authors = s.names(r.Editors) + ", Eds."
+		}
+	}
+	switch {
+	case r.Type == "article":
+		src := italic(r.Container) + " " + bold(r.Year)
+		if r.Volume != "" {
+			src += ", " + italic(r.Volume)
+		}
+		if r.Issue != "" {
+			src += " (" + r.Issue + ")"
+		}
+		if r.Pages != "" {
+			src += ", " + r.Pages
+		}
+		return sentence(authors, r.Title, src, r.link()), nil
+	case r.Type == "inproceedings":
+		return sentence(authors, r.Title, "Presented at "+joinNonEmpty(", ", r.Container, r.Address, r.Year), r.link()), nil
+	case r.Type == "techreport":
+		return sentence(authors, joinNonEmpty("; ", italic(r.Title), joinNonEmpty(", ", joinNonEmpty(": ", r.Institution, r.Address), r.Year)), r.link()), nil
+	case r.Type == "incollection":
+		in := "In " + italic(r.Container)
+		if len(r.Editors) > 0 {
+			in += "; " + s.names(r.Editors) + ", Ed"
+			if len(r.Editors) > 1 {
+				in += "s"
+			}
+			in += "."
+		}
+		in = joinNonEmpty("; ", in, joinNonEmpty(", ", joinNonEmpty(": ", r.Publisher, r.Address), r.Year))
+		if r.Pages != "" {
+			in += "; pp " + r.Pages
+		}
+		return sentence(authors, r.Title, in, r.link()), nil
+	case r.isBook():
+		src := joinNonEmpty(", ", italic(r.Title), r.Edition)
+		if r.Volume != "" {
+			src += "; Vol. " + r.Volume
+		}
+		src = joinNonEmpty("; ", src, joinNonEmpty(", ", joinNonEmpty(": ", r.publisher(), r.Address), r.Year))
+		if r.Chapter != "" {
+			src += "; Chapter " + r.Chapter
+		}
+		if r.Pages != "" {
+			src += ", pp " + r.Pages
+		}
+		return sentence(authors, src, r.link()), nil
+	case r.Type == "phdthesis" || r.Type == "mastersthesis" || r.Type == "thesis":
+		return sentence(authors, r.Title, joinNonEmpty(", ", r.kind(acsKinds), r.Institution, r.Address, r.Year), r.link()), nil
+	case r.Type == "misc" || r.Type == "software" || r.Type == "dataset":
+		title := italic(r.Title)
+		if r.Version != "" {
+			title += ", version " + r.Version
+		}
+		return sentence(authors, joinNonEmpty("; ", title, joinNonEmpty(", ", r.kind(acsKinds), r.HowPublished, r.publisher(), r.Year)), r.Note, r.link()), nil
+	case r.Type == "unpublished":
+		return sentence(authors, r.Title, joinNonEmpty(", ", r.kind(acsKinds), r.Year), r.Note, r.link()), nil
+	case r.Type == "patent":
+		return sentence(authors, r.Title, joinNonEmpty(", ", r.kind(acsKinds)+" "+r.Issue, r.Year), r.link()), nil
+	case r.Type == "online":
+		src := r.link()
+		if r.Accessed != "" {
+			src += " (accessed " + r.Accessed + ")"
+		}
+		return sentence(authors, r.Title, joinNonEmpty(", ", r.Container, r.publisher()), src), nil
+	default:
+		return "", fmt.Errorf("invalid citation type %s", r.Type)
+	}
+}
+
+// joinNonEmpty joins the non-empty elements of parts with sep.
+func joinNonEmpty(sep string, parts ...string) string {
+	var out []string
+	for _, p := range parts {
+		if strings.TrimSpace(p) != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, sep)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"html/template"
+	"testing"
+)
+
+// TestStyles formats an entry of each type, and entries with missing fields,
+// in each of the built-in styles other than the default.
+func TestStyles(t *testing.T) {
+	citations := testBib(t, `
+@article{article,
+  author = {Tessum, Christopher W. and Hill, Jason D. and Marshall, Julian D.},
+  title = {Life cycle air quality impacts},
+  journal = {Proc. Natl. Acad. Sci.},
+  volume = {111}, number = {52}, pages = {18490--18495}, year = {2014}, month = dec,
+  doi = {10.1073/pnas.1406853111},
+}
+@inproceedings{inproceedings, author = {Hill, Jason D.}, title = {A talk}, booktitle = {AGU Fall Meeting}, address = {San Francisco, CA}, year = {2019}, pages = {12--14}}
+@techreport{techreport, author = {Hill, Jason D. and Doe, Jane}, title = {A report}, institution = {EPA}, address = {Washington, DC}, number = {EPA-1}, year = {2010}, url = {https://example.com/r}}
+@incollection{incollection, author = {Hill, Jason D.}, title = {A chapter}, booktitle = {A book}, editor = {Roe, Richard and Poe, Edgar}, publisher = {Springer}, address = {Berlin}, pages = {1--20}, year = {2018}}
+@book{book, author = {Doe, Jane}, title = {A book}, publisher = {Wiley}, address = {Hoboken, NJ}, edition = {2}, year = {2016}}
+@book{editedbook, editor = {Roe, Richard}, title = {Edited volume}, publisher = {Wiley}, year = {2017}}
+@inbook{inbook, author = {Doe, Jane}, title = {A book}, chapter = {3}, pages = {40--50}, publisher = {Wiley}, year = {2016}}
+@phdthesis{phdthesis, author = {Tessum, Christopher W.}, title = {A thesis}, school = {University of Minnesota}, address = {Minneapolis, MN}, year = {2014}}
+@misc{misc, author = {Doe, Jane}, title = {A dataset}, howpublished = {Zenodo}, year = {2021}, url = {https://zenodo.org/x}}
+@unpublished{unpublished, author = {Doe, Jane}, title = {In preparation}, note = {Manuscript in preparation}, year = {2024}}
+@patent{patent, author = {Doe, Jane}, title = {A device}, number = {US 1,234,567}, year = {2012}}
+@online{online, author = {Doe, Jane}, title = {A web page}, url = {https://example.com/p}, urldate = {2020-01-02}, year = {2019}}
+@article{bare, title = {Only a title}}
+@article{noauthor, title = {No author}, journal = {Nature}, year = {2020}}
+@article{noyear, author = {Doe, Jane}, title = {No year}, journal = {Nature}}
+@incollection{noeditor, author = {Doe, Jane}, title = {No editor}, booktitle = {A book}, year = {2018}}
+@misc{onlytitle, title = {Misc title}}
+`)
+	for _, tt := range []struct{ style, key, want string }{
+		{"apa", "article", "Tessum, C. W., Hill, J. D., & Marshall, J. D. (2014). Life cycle air quality impacts. <i>Proc. Natl. Acad. Sci</i>, <i>111</i>(52), 18490–18495. <a href=https://doi.org/10.1073/pnas.1406853111>https://doi.org/10.1073/pnas.1406853111</a>."},
+		{"apa", "inproceedings", "Hill, J. D. (2019). <i>A talk</i> [Conference presentation]. AGU Fall Meeting, San Francisco, CA."},
+		{"apa", "techreport", "Hill, J. D., & Doe, J. (2010). <i>A report</i>. EPA. <a href=https://example.com/r>https://example.com/r</a>."},
+		{"apa", "incollection", "Hill, J. D. (2018). A chapter. In R. Roe & E. Poe (Eds.), <i>A book</i> (pp. 1–20). Springer."},
+		{"apa", "book", "Doe, J. (2016). <i>A book</i> (2nd ed.). Wiley."},
+		{"apa", "editedbook", "Roe, R. (Ed.). (2017). <i>Edited volume</i>. Wiley."},
+		{"apa", "inbook", "Doe, J. (2016). <i>A book</i> (Chapter 3, pp. 40–50). Wiley."},
+		{"apa", "phdthesis", "Tessum, C. W. (2014). <i>A thesis</i> [Doctoral dissertation, University of Minnesota]."},
+		{"apa", "misc", "Doe, J. (2021). <i>A dataset</i>. Zenodo. <a href=https://zenodo.org/x>https://zenodo.org/x</a>."},
+		{"apa", "unpublished", "Doe, J. (2024). <i>In preparation</i> [Unpublished manuscript]. Manuscript in preparation."},
+		{"apa", "patent", "Doe, J. (2012). <i>A device</i> (Patent No. US 1,234,567)."},
+		{"apa", "online", "Doe, J. (2019). <i>A web page</i>. Retrieved January 2, 2020, from <a href=https://example.com/p>https://example.com/p</a>."},
+		{"apa", "bare", "Only a title. (n.d.)."},
+		{"apa", "noauthor", "No author. (2020). <i>Nature</i>."},
+		{"apa", "noyear", "Doe, J. (n.d.). No year. <i>Nature</i>."},
+		{"apa", "noeditor", "Doe, J. (2018). No editor. In <i>A book</i>."},
+		{"apa", "onlytitle", "<i>Misc title</i>. (n.d.)."},
+		{"chicago", "article", "Tessum, Christopher W., Jason D. Hill, and Julian D. Marshall. 2014. “Life cycle air quality impacts.” <i>Proc. Natl. Acad. Sci</i> 111 (52): 18490–18495. <a href=https://doi.org/10.1073/pnas.1406853111>https://doi.org/10.1073/pnas.1406853111</a>."},
+		{"chicago", "inproceedings", "Hill, Jason D. 2019. “A talk.” Paper presented at AGU Fall Meeting, San Francisco, CA."},
+		{"chicago", "techreport", "Hill, Jason D., and Jane Doe. 2010. <i>A report</i>. Washington, DC: EPA. <a href=https://example.com/r>https://example.com/r</a>."},
+		{"chicago", "incollection", "Hill, Jason D. 2018. “A chapter.” In <i>A book</i>, edited by Richard Roe and Edgar Poe, 1–20. Berlin: Springer."},
+		{"chicago", "book", "Doe, Jane. 2016. <i>A book</i>. 2nd ed. Hoboken, NJ: Wiley."},
+		{"chicago", "editedbook", "Roe, Richard, ed. 2017. <i>Edited volume</i>. Wiley."},
+		{"chicago", "inbook", "Doe, Jane. 2016. <i>A book</i>. Chapter 3, 40–50. Wiley."},
+		{"chicago", "phdthesis", "Tessum, Christopher W. 2014. “A thesis.” PhD diss., University of Minnesota."},
+		{"chicago", "misc", "Doe, Jane. 2021. <i>A dataset</i>. Zenodo. <a href=https://zenodo.org/x>https://zenodo.org/x</a>."},
+		{"chicago", "unpublished", "Doe, Jane. 2024. “In preparation.” Unpublished manuscript. Manuscript in preparation."},
+		{"chicago", "patent", "Doe, Jane. 2012. “A device.” Patent US 1,234,567."},
+		{"chicago", "online", "Doe, Jane. 2019. “A web page.” Accessed January 2, 2020. <a href=https://example.com/p>https://example.com/p</a>."},
+		{"chicago", "bare", "“Only a title.” n.d."},
+		{"chicago", "noauthor", "“No author.” 2020. <i>Nature</i>."},
+		{"chicago", "noyear", "Doe, Jane. n.d. “No year.” <i>Nature</i>."},
+		{"chicago", "noeditor", "Doe, Jane. 2018. “No editor.” In <i>A book</i>."},
+		{"chicago", "onlytitle", "<i>Misc title</i>. n.d."},
+		{"acs", "article", "Tessum, C. W.; Hill, J. D.; Marshall, J. D. Life cycle air quality impacts. <i>Proc. Natl. Acad. Sci</i> <strong>2014</strong>, <i>111</i> (52), 18490–18495. <a href=https://doi.org/10.1073/pnas.1406853111>https://doi.org/10.1073/pnas.1406853111</a>."},
+		{"acs", "inproceedings", "Hill, J. D. A talk. Presented at AGU Fall Meeting, San Francisco, CA, 2019."},
+		{"acs", "techreport", "Hill, J. D.; Doe, J. <i>A report</i>; EPA: Washington, DC, 2010. <a href=https://example.com/r>https://example.com/r</a>."},
+		{"acs", "incollection", "Hill, J. D. A chapter. In <i>A book</i>; Roe, R.; Poe, E., Eds.; Springer: Berlin, 2018; pp 1–20."},
+		{"acs", "book", "Doe, J. <i>A book</i>, 2nd ed.; Wiley: Hoboken, NJ, 2016."},
+		{"acs", "editedbook", "Roe, R., Ed. <i>Edited volume</i>; Wiley, 2017."},
+		{"acs", "inbook", "Doe, J. <i>A book</i>; Wiley, 2016; Chapter 3, pp 40–50."},
+		{"acs", "phdthesis", "Tessum, C. W. A thesis. Ph.D. Dissertation, University of Minnesota, Minneapolis, MN, 2014."},
+		{"acs", "misc", "Doe, J. <i>A dataset</i>; Zenodo, 2021. <a href=https://zenodo.org/x>https://zenodo.org/x</a>."},
+		{"acs", "unpublished", "Doe, J. In preparation. Unpublished work, 2024. Manuscript in preparation."},
+		{"acs", "patent", "Doe, J. A device. Patent US 1,234,567, 2012."},
+		{"acs", "online", "Doe, J. A web page. <a href=https://example.com/p>https://example.com/p</a> (accessed January 2, 2020)."},
+		{"acs", "bare", "Only a title."},
+		{"acs", "noauthor", "No author. <i>Nature</i> <strong>2020</strong>."},
+		{"acs", "noyear", "Doe, J. No year. <i>Nature</i>."},
+		{"acs", "noeditor", "Doe, J. No editor. In <i>A book</i>; 2018."},
+		{"acs", "onlytitle", "<i>Misc title</i>."},
+	} {
+		got, err := styles[tt.style].Format(citations[template.HTML(tt.key)])
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.style, tt.key, err)
+		} else if got != tt.want {
+			t.Errorf("%s %s:\n got %q\nwant %q", tt.style, tt.key, got, tt.want)
+		}
+	}
+}
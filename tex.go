@@ -0,0 +1,383 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// latexClasses are the document classes that a document can be exported
+// to LaTeX with: a plain article, or moderncv.
+var latexClasses = []string{"article", "moderncv"}
+
+// latexCitationModes are the ways a document's citations can be exported
+// to LaTeX: as formatted by the document's style, or as \nocite commands
+// for BibLaTeX to format from the original bibliographies.
+var latexCitationModes = []string{"formatted", "biblatex"}
+
+// LaTeXOptions select how a document is exported to LaTeX.
+type LaTeXOptions struct {
+	Class     string `yaml:"class"`     // one of latexClasses; default article
+	Citations string `yaml:"citations"` // one of latexCitationModes; default formatted
+
+	line int
+}
+
+func (o *LaTeXOptions) UnmarshalYAML(n *yaml.Node) error {
+	o.line = n.Line
+	type plain LaTeXOptions
+	return decodeStrict(n, (*plain)(o), "latex options")
+}
+
+func (o *LaTeXOptions) validate() error {
+	if o.Class != "" && !slices.Contains(latexClasses, o.Class) {
+		return fmt.Errorf("unknown class '%s' (available: %s)", o.Class, strings.Join(latexClasses, ", "))
+	}
+	if o.Citations != "" && !slices.Contains(latexCitationModes, o.Citations) {
+		return fmt.Errorf("unknown citations mode '%s' (available: %s)", o.Citations, strings.Join(latexCitationModes, ", "))
+	}
+	return nil
+}
+
+// latexPapers are the geometry package options for each paper size.
+var latexPapers = map[string]string{
+	"letter":  "letterpaper",
+	"legal":   "legalpaper",
+	"tabloid": "paperwidth=11in,paperheight=17in",
+	"a3":      "a3paper",
+	"a4":      "a4paper",
+	"a5":      "a5paper",
+}
+
+// writeTeX writes r as a LaTeX document. Bibliographies used with the
+// biblatex citation mode are referred to relative to the directory of
+// filename.
+func writeTeX(r *rendering, filename string) error {
+	w := &texWriter{r: r, moderncv: r.LaTeX.Class == "moderncv", biblatex: r.LaTeX.Citations == "biblatex"}
+	if err := w.document(filepath.Dir(filename)); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, []byte(w.String()), 0644)
+}
+
+// texWriter writes the parts of a CV as LaTeX.
+type texWriter struct {
+	strings.Builder
+	r                  *rendering
+	moderncv, biblatex bool
+}
+
+func (w *texWriter) printf(format string, args ...interface{}) {
+	fmt.Fprintf(w, format, args...)
+}
+
+func (w *texWriter) document(dir string) error {
+	r := w.r
+	// The nearest font size to the scale of the document.
+	size := min(max(math.Round(11*r.Print.Scale), 10), 12)
+	class := "article"
+	if w.moderncv {
+		class = "moderncv"
+	}
+	w.printf("\\documentclass[%gpt]{%s}\n", size, class)
+	if w.moderncv {
+		w.printf("\\moderncvstyle{classic}\n\\moderncvcolor{blue}\n")
+	}
+	w.printf("\\usepackage[utf8]{inputenc}\n\\usepackage[T1]{fontenc}\n\\usepackage{textcomp}\n")
+	w.printf("\\usepackage[normalem]{ulem}\n")
+	m := r.Print.Margins
+	geometry := fmt.Sprintf("%s,top=%gin,bottom=%gin,left=%gin,right=%gin", latexPapers[r.Print.Paper], m.Top, m.Bottom, m.Left, m.Right)
+	if r.Print.Landscape {
+		geometry += ",landscape"
+	}
+	w.printf("\\usepackage[%s]{geometry}\n", geometry)
+	if !w.moderncv {
+		// moderncv loads hyperref itself.
+		w.printf("\\usepackage[hidelinks]{hyperref}\n")
+	}
+	if r.Header != nil || r.Footer != nil {
+		w.printf("\\usepackage{fancyhdr}\n\\usepackage{lastpage}\n\\pagestyle{fancy}\n\\fancyhf{}\n")
+		values := r.pageValues(`\thepage{}`, `\pageref*{LastPage}`, escapeLaTeX)
+		for _, p := range []struct {
+			cmd  string
+			text *PageText
+		}{{"fancyhead", r.Header}, {"fancyfoot", r.Footer}} {
+			if p.text == nil {
+				continue
+			}
+			for i, s := range p.text.parts() {
+				if s != "" {
+					w.printf("\\%s[%s]{\\footnotesize %s}\n", p.cmd, "LCR"[i:i+1], expandPage(s, values, escapeLaTeX))
+				}
+			}
+		}
+		if r.Header == nil {
+			w.printf("\\renewcommand{\\headrulewidth}{0pt}\n")
+		}
+	}
+	if w.biblatex {
+		w.printf("\\usepackage[style=numeric,sorting=none,defernumbers=true]{biblatex}\n")
+		// Entries are numbered down from the count set before each list,
+		// as in the other formats, rather than from 1 in each refsection.
+		w.printf("\\newcounter{cvref}\n")
+		w.printf("\\defbibenvironment{cvreversed}{\\list{\\arabic{cvref}.\\addtocounter{cvref}{-1}}{\\settowidth{\\labelwidth}{000.}\\setlength{\\leftmargin}{\\labelwidth}\\addtolength{\\leftmargin}{\\labelsep}}}{\\endlist}{\\item}\n")
+		for _, bib := range r.bibliographies {
+			rel, err := relPath(dir, bib)
+			if err != nil {
+				return err
+			}
+			w.printf("\\addbibresource{%s}\n", filepath.ToSlash(rel))
+		}
+	}
+	var info []string
+	for _, e := range r.pdfInfo() {
+		info = append(info, fmt.Sprintf("pdf%s={%s}", strings.ToLower(e[0]), escapeLaTeX(e[1])))
+	}
+	w.printf("\\AtBeginDocument{\\hypersetup{%s}}\n", strings.Join(info, ", "))
+	if w.moderncv {
+		w.ownerModernCV(r.Owner)
+	}
+
+	w.printf("\n\\begin{document}\n")
+	if w.moderncv {
+		w.printf("\\makecvtitle\n")
+	} else {
+		w.ownerArticle(r.Owner)
+	}
+	for _, s := range r.Sections {
+		if err := w.section(s); err != nil {
+			return err
+		}
+	}
+	w.printf("\\end{document}\n")
+	return nil
+}
+
+// ownerArticle writes the owner's name on the left and their contact
+// details on the right, followed by a rule.
+func (w *texWriter) ownerArticle(o Owner) {
+	w.printf("\\noindent\\begin{minipage}[t]{0.45\\textwidth}\n{\\LARGE %s}\n\\end{minipage}\\hfill\n", escapeLaTeX(o.Name))
+	w.printf("\\begin{minipage}[t]{0.53\\textwidth}\\raggedleft\n")
+	var lines []string
+	for _, l := range o.contactLines() {
+		lines = append(lines, latexText(parseFragment(l)))
+	}
+	w.printf("%s\n\\end{minipage}\n\\par\\medskip\\hrule\\bigskip\n", strings.Join(lines, "\\\\\n"))
+}
+
+// ownerModernCV sets the owner's name and contact details for moderncv's
+// title.
+func (w *texWriter) ownerModernCV(o Owner) {
+	first, last := "", o.Name
+	if i := strings.LastIndex(o.Name, " "); i >= 0 {
+		first, last = o.Name[:i], o.Name[i+1:]
+	}
+	w.printf("\\name{%s}{%s}\n", escapeLaTeX(first), escapeLaTeX(last))
+	if o.Email != "" {
+		w.printf("\\email{%s}\n", escapeLaTeX(o.Email))
+	}
+	if o.Website != "" {
+		// moderncv adds the scheme itself.
+		u := strings.TrimPrefix(strings.TrimPrefix(o.Website, "https://"), "http://")
+		w.printf("\\homepage{%s}\n", escapeLaTeX(strings.TrimSuffix(u, "/")))
+	}
+	if o.ORCID != "" {
+		w.printf("\\extrainfo{\\href{https://orcid.org/%s}{ORCID: %s}}\n", escapeURL(o.ORCID), escapeLaTeX(o.ORCID))
+	}
+}
+
+// section writes the heading and contents of s.
+func (w *texWriter) section(s Section) error {
+	r := w.r
+	heading := string(s.Name)
+	// BibLaTeX formats the entries without the marks that a legend explains.
+	if !w.biblatex {
+		heading += string(r.legend(s.Citations, s.Authors))
+	}
+	heading = latexText(parseFragment(heading))
+	// Neither heading is numbered, so the label needs an anchor of its own.
+	if w.moderncv {
+		w.printf("\n\\section{%s}\\phantomsection\\label{%s}\n", heading, s.ID)
+	} else {
+		w.printf("\n\\section*{\\textsc{%s}}\\phantomsection\\label{%s}\n", heading, s.ID)
+	}
+
+	var groups []citationGroup
+	if len(s.Citations) > 0 {
+		groups = r.groups(s)
+	}
+	for _, g := range groups {
+		if s.GroupByYear {
+			if w.moderncv {
+				w.printf("\\subsection{%s}\n", escapeLaTeX(g.Year))
+			} else {
+				w.printf("\\subsection*{%s}\n", escapeLaTeX(g.Year))
+			}
+		}
+		if w.biblatex {
+			keys := make([]string, len(g.Citations))
+			for i, key := range g.Citations {
+				keys[i] = string(key)
+			}
+			w.printf("\\begin{refsection}\n\\nocite{%s}\n\\setcounter{cvref}{%d}\n", strings.Join(keys, ","), g.Start)
+			w.printf("\\printbibliography[heading=none,env=cvreversed]\n\\end{refsection}\n")
+			continue
+		}
+		if !w.moderncv {
+			w.printf("\\begin{enumerate}\n")
+		}
+		for i, key := range g.Citations {
+			text, err := r.ref(key, s.Authors)
+			if err != nil {
+				return err
+			}
+			if w.moderncv {
+				w.printf("\\cvitem{%d.}{%s}\n", g.Start-i, latexText(parseFragment(string(text))))
+			} else {
+				w.printf("\\item[%d.] %s\n", g.Start-i, latexText(parseFragment(string(text))))
+			}
+		}
+		if !w.moderncv {
+			w.printf("\\end{enumerate}\n")
+		}
+	}
+
+	if len(s.Items) == 0 {
+		return nil
+	}
+	if !w.moderncv {
+		w.printf("\\begin{itemize}\n")
+	}
+	for _, item := range s.Items {
+		name := latexText(parseFragment(string(item.Name)))
+		time := latexText(parseFragment(string(item.Time)))
+		var description string
+		if item.Description != "" {
+			description = "\\newline\n" + latexText(parseFragment(string(item.Description)))
+		}
+		switch {
+		case w.moderncv:
+			w.printf("\\cvitem{%s}{%s%s}\n", time, name, description)
+		case time != "":
+			w.printf("\\item[] %s\\hfill %s%s\n", name, time, description)
+		default:
+			w.printf("\\item[] %s%s\n", name, description)
+		}
+	}
+	if !w.moderncv {
+		w.printf("\\end{itemize}\n")
+	}
+	return nil
+}
+
+// relPath returns the path of file relative to dir.
+func relPath(dir, file string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(absDir, abs)
+}
+
+// latexText converts runs to LaTeX.
+func latexText(runs []textRun) string {
+	var b strings.Builder
+	for _, run := range runs {
+		if run.Break {
+			b.WriteString("\\newline ")
+			continue
+		}
+		s := escapeLaTeX(run.Text)
+		for _, f := range []struct {
+			on  bool
+			cmd string
+		}{
+			{run.Italic, `\textit{%s}`},
+			{run.Bold, `\textbf{%s}`},
+			{run.Underline, `\uline{%s}`},
+			{run.Small, `{\small %s}`},
+			{run.Sup, `\textsuperscript{%s}`},
+			{run.Sub, `\textsubscript{%s}`},
+		} {
+			if f.on {
+				s = fmt.Sprintf(f.cmd, s)
+			}
+		}
+		if run.Href != "" {
+			s = fmt.Sprintf(`\href{%s}{%s}`, escapeURL(run.Href), s)
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+// latexSymbols are characters that must be escaped in LaTeX, and
+// characters that LaTeX's UTF-8 input encoding does not understand.
+var latexSymbols = map[rune]string{
+	'\\':     `\textbackslash{}`,
+	'{':      `\{`,
+	'}':      `\}`,
+	'$':      `\$`,
+	'&':      `\&`,
+	'#':      `\#`,
+	'%':      `\%`,
+	'_':      `\_`,
+	'^':      `\textasciicircum{}`,
+	'~':      `\textasciitilde{}`,
+	'\u00a0': `~`,
+	'†':      `\textdagger{}`,
+	'‡':      `\textdaggerdbl{}`,
+}
+
+// latexMath are the math commands for the symbols that decodeLaTeX
+// produces and that LaTeX's UTF-8 input encoding does not understand.
+var latexMath = map[rune]string{
+	'α': `\alpha`, 'β': `\beta`, 'γ': `\gamma`, 'δ': `\delta`, 'ϵ': `\epsilon`,
+	'ε': `\varepsilon`, 'ζ': `\zeta`, 'η': `\eta`, 'θ': `\theta`, 'ϑ': `\vartheta`,
+	'ι': `\iota`, 'κ': `\kappa`, 'λ': `\lambda`, 'μ': `\mu`, 'ν': `\nu`, 'ξ': `\xi`,
+	'π': `\pi`, 'ρ': `\rho`, 'σ': `\sigma`, 'τ': `\tau`, 'υ': `\upsilon`,
+	'ϕ': `\phi`, 'φ': `\varphi`, 'χ': `\chi`, 'ψ': `\psi`, 'ω': `\omega`,
+	'Γ': `\Gamma`, 'Δ': `\Delta`, 'Θ': `\Theta`, 'Λ': `\Lambda`, 'Ξ': `\Xi`,
+	'Π': `\Pi`, 'Σ': `\Sigma`, 'Υ': `\Upsilon`, 'Φ': `\Phi`, 'Ψ': `\Psi`,
+	'Ω': `\Omega`,
+
+	'−': `-`, '∓': `\mp`, '≤': `\leq`, '≥': `\geq`, '≠': `\neq`,
+	'≈': `\approx`, '∼': `\sim`, '≃': `\simeq`, '∝': `\propto`, '∞': `\infty`,
+	'∘': `\circ`, '→': `\rightarrow`, '←': `\leftarrow`, '↔': `\leftrightarrow`,
+	'⇒': `\Rightarrow`, '∂': `\partial`, '∇': `\nabla`, '∑': `\sum`,
+	'∏': `\prod`, '∫': `\int`, 'ℓ': `\ell`, '′': `\prime`, '⋯': `\cdots`,
+	'∈': `\in`, '≪': `\ll`, '≫': `\gg`, '⟨': `\langle`, '⟩': `\rangle`,
+}
+
+// escapeLaTeX escapes s for use as text in LaTeX.
+func escapeLaTeX(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		c, n := utf8.DecodeRuneInString(s)
+		if e, ok := latexSymbols[c]; ok {
+			b.WriteString(e)
+		} else if e, ok := latexMath[c]; ok {
+			b.WriteString(`\ensuremath{` + e + `}`)
+		} else {
+			b.WriteRune(c)
+		}
+		s = s[n:]
+	}
+	return b.String()
+}
+
+// escapeURL escapes the characters of a URL that hyperref's \href does
+// not accept as they are.
+func escapeURL(u string) string {
+	return strings.NewReplacer("#", `\#`, "%", `\%`, "{", `\{`, "}", `\}`).Replace(u)
+}
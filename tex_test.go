@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestEscapeLaTeX(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"Tessum & Hill", `Tessum \& Hill`},
+		{"50% of $5 #1", `50\% of \$5 \#1`},
+		{`a_b^c~d\e{f}`, `a\_b\textasciicircum{}c\textasciitilde{}d\textbackslash{}e\{f\}`},
+		{"Müller–São Paulo", "Müller–São Paulo"},
+		{"a b†", `a~b\textdagger{}`},
+		{"α ≤ 5", `\ensuremath{\alpha} \ensuremath{\leq} 5`},
+		{"10−3 ϵ∘C → x", `10\ensuremath{-}3 \ensuremath{\epsilon}\ensuremath{\circ}C \ensuremath{\rightarrow} x`},
+	} {
+		if got := escapeLaTeX(tt.in); got != tt.want {
+			t.Errorf("escapeLaTeX(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteTeX(t *testing.T) {
+	for _, r := range testRenderings(t) {
+		checkGolden(t, r.ID+".tex", export(t, r, writeTeX))
+	}
+}
+
+func TestWriteTeXBibLaTeX(t *testing.T) {
+	r := testRenderings(t)[0]
+	r.LaTeX.Citations = "biblatex"
+	w := &texWriter{r: r, biblatex: true}
+	if err := w.document("testdata"); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, r.ID+"_biblatex.tex", []byte(w.String()))
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// textWidth is the number of characters per line of plain text documents.
+const textWidth = 78
+
+// writeText writes r as a plain text document with fixed-width lines.
+func writeText(r *rendering, filename string) error {
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(r.Owner.Name) + "\n")
+	for _, l := range r.Owner.contactLines() {
+		for _, l := range textWrap(plainTextWithLinks(parseFragment(l)), textWidth) {
+			b.WriteString(l + "\n")
+		}
+	}
+	b.WriteString(strings.Repeat("=", textWidth) + "\n")
+
+	indent := textWidth / 12
+	for _, s := range r.Sections {
+		heading := strings.ToUpper(plainTextWithLinks(parseFragment(string(s.Name))))
+		if legend := plainTextWithLinks(parseFragment(string(r.legend(s.Citations, s.Authors)))); legend != "" {
+			heading += " " + legend
+		}
+		for _, l := range textWrap(heading, textWidth) {
+			fmt.Fprintf(&b, "\n%s", l)
+		}
+		fmt.Fprintf(&b, "\n%s\n", strings.Repeat("-", min(utf8.RuneCountInString(heading), textWidth)))
+
+		var groups []citationGroup
+		if len(s.Citations) > 0 {
+			groups = r.groups(s)
+		}
+		for _, g := range groups {
+			if s.GroupByYear {
+				fmt.Fprintf(&b, "\n%s%s\n", strings.Repeat(" ", indent), g.Year)
+			}
+			for i, key := range g.Citations {
+				text, err := r.ref(key, s.Authors)
+				if err != nil {
+					return err
+				}
+				number := fmt.Sprintf("%*d. ", indent-2, g.Start-i)
+				for j, l := range textWrap(plainTextWithLinks(parseFragment(string(text))), textWidth-indent) {
+					if j == 0 {
+						fmt.Fprintf(&b, "\n%s%s", number, l)
+					} else {
+						fmt.Fprintf(&b, "\n%s%s", strings.Repeat(" ", indent), l)
+					}
+				}
+				b.WriteString("\n")
+			}
+		}
+		for _, item := range s.Items {
+			time := plainTextWithLinks(parseFragment(string(item.Time)))
+			width := textWidth - indent
+			if time != "" {
+				width -= utf8.RuneCountInString(time) + 2
+			}
+			lines := textWrap(plainTextWithLinks(parseFragment(string(item.Name))), width)
+			if item.Description != "" {
+				lines = append(lines, textWrap(plainTextWithLinks(parseFragment(string(item.Description))), width)...)
+			}
+			for j, l := range lines {
+				right := ""
+				if j == 0 {
+					right = time
+				}
+				fmt.Fprintf(&b, "\n%s%s", strings.Repeat(" ", indent), textJustify(l, right, textWidth-indent))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return os.WriteFile(filename, []byte(b.String()), 0644)
+}
+
+// plainTextWithLinks returns the text of runs without formatting, like
+// plainText, but with the address of each link following its text if
+// they differ.
+func plainTextWithLinks(runs []textRun) string {
+	var b strings.Builder
+	for i, run := range runs {
+		if run.Break {
+			b.WriteString(" ")
+			continue
+		}
+		b.WriteString(run.Text)
+		if run.Href == "" || i+1 < len(runs) && runs[i+1].Href == run.Href {
+			continue
+		}
+		if text := strings.TrimSpace(linkText(runs, i)); text != run.Href {
+			fmt.Fprintf(&b, " <%s>", run.Href)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// linkText returns the text of the link that ends with runs[i].
+func linkText(runs []textRun, i int) string {
+	j := i
+	for j > 0 && runs[j-1].Href == runs[i].Href && !runs[j-1].Break {
+		j--
+	}
+	var b strings.Builder
+	for _, run := range runs[j : i+1] {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// textWrap splits s into lines of at most width characters, breaking at
+// spaces where possible.
+func textWrap(s string, width int) []string {
+	var lines []string
+	var line []rune
+	for _, word := range strings.Fields(s) {
+		w := []rune(word)
+		if len(line) > 0 && len(line)+1+len(w) > width {
+			lines = append(lines, string(line))
+			line = nil
+		}
+		if len(line) > 0 {
+			line = append(line, ' ')
+		}
+		line = append(line, w...)
+		for len(line) > width {
+			lines = append(lines, string(line[:width]))
+			line = line[width:]
+		}
+	}
+	if len(line) > 0 || len(lines) == 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// textJustify returns left and right on a line of width characters, with
+// right aligned to the end, or separated by two spaces if they are too
+// long.
+func textJustify(left, right string, width int) string {
+	if right == "" {
+		return left
+	}
+	pad := width - utf8.RuneCountInString(left) - utf8.RuneCountInString(right)
+	return left + strings.Repeat(" ", max(pad, 2)) + right
+}
@@ -0,0 +1,9 @@
+package main
+
+import "testing"
+
+func TestWriteText(t *testing.T) {
+	for _, r := range testRenderings(t) {
+		checkGolden(t, r.ID+".txt", export(t, r, writeText))
+	}
+}